@@ -0,0 +1,109 @@
+// Package audio decodes music files with ffmpeg and analyzes their samples.
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// PCM is decoded audio, one slice of samples (between -1 and 1) per channel.
+type PCM struct {
+	SampleRate int
+	Channels   [][]float64
+}
+
+// Len is the number of samples of each channel.
+func (p PCM) Len() int {
+	if len(p.Channels) == 0 {
+		return 0
+	}
+	return len(p.Channels[0])
+}
+
+// FFmpeg is the command that decodes the files, and FFprobe the one that
+// reads their tags.
+var (
+	FFmpeg  = "ffmpeg"
+	FFprobe = "ffprobe"
+)
+
+// Decode decodes a file to PCM at the given sample rate and number of
+// channels, with ffmpeg. It reads all the formats ffmpeg knows, which
+// includes the formats of the formats package.
+func Decode(path string, sampleRate, channels int) (PCM, error) {
+	pcm := PCM{SampleRate: sampleRate, Channels: make([][]float64, channels)}
+	err := stream(path, sampleRate, channels, func(frame []float64) {
+		for c, s := range frame {
+			pcm.Channels[c] = append(pcm.Channels[c], s)
+		}
+	})
+	if err != nil {
+		return PCM{}, err
+	}
+	return pcm, nil
+}
+
+// DecodeMono decodes a file to mono samples at the given sample rate, with
+// ffmpeg. They are kept as float32, which halves the memory of long songs.
+func DecodeMono(path string, sampleRate int) ([]float32, error) {
+	samples := make([]float32, 0)
+	err := stream(path, sampleRate, 1, func(frame []float64) {
+		samples = append(samples, float32(frame[0]))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return samples, nil
+}
+
+// stream decodes a file with ffmpeg and passes its frames, one sample per
+// channel, to consume, so that the file is never fully in memory. The frame
+// is reused between the calls.
+func stream(path string, sampleRate, channels int, consume func(frame []float64)) error {
+	cmd := exec.Command(FFmpeg, "-v", "error", "-i", path, "-f", "f32le", "-ac", strconv.Itoa(channels), "-ar", strconv.Itoa(sampleRate), "-")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	readErr := readFloat32LE(stdout, channels, consume)
+	// ffmpeg would block on a full pipe
+	_, _ = io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+	if readErr != nil {
+		return fmt.Errorf("%s: %w", path, readErr)
+	}
+	return nil
+}
+
+// readFloat32LE reads interleaved little-endian float32 samples and passes
+// them to consume, frame by frame. A partial frame at the end is ignored.
+func readFloat32LE(r io.Reader, channels int, consume func(frame []float64)) error {
+	reader := bufio.NewReaderSize(r, 1<<16)
+	data := make([]byte, 4*channels)
+	frame := make([]float64, channels)
+	for {
+		if _, err := io.ReadFull(reader, data); err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		for c := range frame {
+			frame[c] = float64(math.Float32frombits(binary.LittleEndian.Uint32(data[4*c:])))
+		}
+		consume(frame)
+	}
+}
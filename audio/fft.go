@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// fft computes in place the discrete Fourier transform of values, whose
+// length must be a power of 2, or its inverse (without the 1/n factor).
+func fft(values []complex128, inverse bool) {
+	n := len(values)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Rect(1, sign*2*math.Pi/float64(size))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				a, b := values[start+k], values[start+k+size/2]*w
+				values[start+k], values[start+k+size/2] = a+b, a-b
+				w *= step
+			}
+		}
+	}
+}
+
+// autocorrelation computes sum(x[t] * x[t+lag]) for all the lags, with
+// FFTs.
+func autocorrelation(x []float64) []float64 {
+	size := 1
+	for size < 2*len(x) {
+		size <<= 1
+	}
+	values := make([]complex128, size)
+	for i, v := range x {
+		values[i] = complex(v, 0)
+	}
+	fft(values, false)
+	for i, v := range values {
+		values[i] = complex(real(v)*real(v)+imag(v)*imag(v), 0)
+	}
+	fft(values, true)
+
+	rv := make([]float64, len(x))
+	for i := range rv {
+		rv[i] = real(values[i]) / float64(size)
+	}
+	return rv
+}
@@ -0,0 +1,25 @@
+package audio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func Test_autocorrelation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	x := make([]float64, 300)
+	for i := range x {
+		x[i] = rng.Float64()*2 - 1
+	}
+
+	got := autocorrelation(x)
+	assert.Len(t, got, len(x))
+	for _, lag := range []int{0, 1, 17, 150, 299} {
+		var want float64
+		for i := 0; i+lag < len(x); i++ {
+			want += x[i] * x[i+lag]
+		}
+		assert.InDelta(t, want, got[lag], 1e-9, "lag %d", lag)
+	}
+}
@@ -0,0 +1,234 @@
+package audio
+
+import (
+	"math"
+)
+
+// LoopPoints is a loop found in the samples of a song, from Start to End (in
+// samples). Confidence is 1 when what follows End is an exact copy of the
+// loop, and 0 when it has nothing in common with it.
+type LoopPoints struct {
+	Start      int
+	End        int
+	SampleRate int
+	Confidence float64
+}
+
+func (l LoopPoints) StartMicro() int {
+	return int(int64(l.Start) * 1000000 / int64(l.SampleRate))
+}
+
+func (l LoopPoints) EndMicro() int {
+	return int(int64(l.End) * 1000000 / int64(l.SampleRate))
+}
+
+const (
+	// minLoopSec is the shortest loop FindLoop looks for, so that the
+	// repeated bars of a song are not taken for its loop.
+	minLoopSec = 5
+	// minRepeatSec is how much of the loop must be repeated after its end.
+	minRepeatSec = 2
+	// analysisRate is the sample rate at which the loop length is searched
+	// first.
+	analysisRate = 2000
+	// matchWindowSec is the duration of the windows compared to find the
+	// loop start, and matchThreshold the relative error under which two
+	// windows are the same.
+	matchWindowSec = 0.05
+	matchThreshold = 0.1
+	// refineWindowSec is how much of the song is compared to refine the loop
+	// length, at the end of the song where it is repeated.
+	refineWindowSec = 30
+)
+
+// FindLoop finds the loop of a song: the song is played once, up to the loop
+// end, then it goes back to the loop start, so the samples after the loop end
+// are a copy of the samples after the loop start.
+//
+// The length of the loop is the lag that maximizes the autocorrelation of the
+// song, found with FFTs on a downsampled copy, then refined on all the
+// samples around the end of the song. The loop start is the first sample
+// from which the song matches its copy one loop later. It takes the mono
+// samples of the song (see DecodeMono), and returns false when no loop is
+// found.
+func FindLoop(x []float32, sampleRate int) (LoopPoints, bool) {
+	factor := sampleRate / analysisRate
+	if factor < 1 {
+		factor = 1
+	}
+
+	coarse, found := bestLag(decimate(x, factor), sampleRate/factor)
+	if !found {
+		return LoopPoints{}, false
+	}
+	length := refineLag(x, coarse*factor, factor, refineWindowSec*sampleRate)
+
+	start, found := loopStart(x, length, sampleRate)
+	if !found {
+		return LoopPoints{}, false
+	}
+	return LoopPoints{
+		Start:      start,
+		End:        start + length,
+		SampleRate: sampleRate,
+		Confidence: similarity(x, start, length, minInt(length, len(x)-length-start)),
+	}, true
+}
+
+// decimate keeps the mean of each block of factor samples.
+func decimate(x []float32, factor int) []float64 {
+	rv := make([]float64, len(x)/factor)
+	for i := range rv {
+		var sum float64
+		for _, s := range x[i*factor : (i+1)*factor] {
+			sum += float64(s)
+		}
+		rv[i] = sum / float64(factor)
+	}
+	return rv
+}
+
+// bestLag is the lag with the highest autocorrelation, normalized by the
+// energy of the overlapping samples, among the possible loop lengths.
+func bestLag(x []float64, sampleRate int) (int, bool) {
+	minLag := minLoopSec * sampleRate
+	maxLag := len(x) - minRepeatSec*sampleRate
+	if minLag >= maxLag {
+		return 0, false
+	}
+
+	r := autocorrelation(x)
+	// energy[i] is the energy of the first i samples
+	energy := make([]float64, len(x)+1)
+	for i, s := range x {
+		energy[i+1] = energy[i] + s*s
+	}
+
+	best, bestScore := 0, 0.0
+	for lag := minLag; lag <= maxLag; lag++ {
+		head := energy[len(x)-lag]
+		tail := energy[len(x)] - energy[lag]
+		if head == 0 || tail == 0 {
+			continue
+		}
+		if score := r[lag] / math.Sqrt(head*tail); score > bestScore {
+			best, bestScore = lag, score
+		}
+	}
+	return best, bestScore > 0
+}
+
+// refineLag finds the most similar lag in the samples around the lag found
+// in the downsampled samples. Only the last window samples that have a copy
+// one lag earlier are compared, the end of a song being repeated.
+func refineLag(x []float32, lag, radius, window int) int {
+	best, bestScore := lag, math.Inf(-1)
+	for l := lag - radius; l <= lag+radius; l++ {
+		if l <= 0 || l >= len(x) {
+			continue
+		}
+		n := minInt(len(x)-l, window)
+		if score := similarity(x, len(x)-l-n, l, n); score > bestScore {
+			best, bestScore = l, score
+		}
+	}
+	return best
+}
+
+// similarity compares the n samples from start with the n samples one lag
+// later: 1 when they are the same, around 0 when they are unrelated.
+func similarity(x []float32, start, lag, n int) float64 {
+	var product, energy float64
+	for i := start; i < start+n; i++ {
+		a, b := float64(x[i]), float64(x[i+lag])
+		product += a * b
+		energy += a*a + b*b
+	}
+	if energy == 0 {
+		return 0
+	}
+	return math.Max(0, 2*product/energy)
+}
+
+// loopStart finds the first window that matches the window one loop later,
+// and is followed by other matching windows. The sample where the matching
+// starts is then searched around this window.
+func loopStart(x []float32, lag, sampleRate int) (int, bool) {
+	window := int(matchWindowSec * float64(sampleRate))
+	if window < 1 {
+		window = 1
+	}
+	count := (len(x) - lag) / window
+	matches := make([]bool, count)
+	for k := range matches {
+		matches[k] = 1-similarity(x, k*window, lag, window) < matchThreshold
+	}
+
+	const followers = 20
+	for k := range matches {
+		if !matches[k] {
+			continue
+		}
+		matched, total := 0, 0
+		for _, m := range matches[k:minInt(k+followers, count)] {
+			total++
+			if m {
+				matched++
+			}
+		}
+		if float64(matched) >= 0.8*float64(total) {
+			return changePoint(x, lag, maxInt(0, (k-1)*window), minInt((k+1)*window, len(x)-lag)), true
+		}
+	}
+	return 0, false
+}
+
+// changePoint finds the sample t of [from, to) such that the samples before
+// t differ from the samples one loop later, and the samples after t are the
+// same, with the fewest exceptions.
+func changePoint(x []float32, lag, from, to int) int {
+	var mean float64
+	for i := from; i < to; i++ {
+		a, b := float64(x[i]), float64(x[i+lag])
+		mean += (a*a + b*b) / 2
+	}
+	threshold := matchThreshold * mean / float64(to-from)
+	same := func(i int) bool {
+		d := float64(x[i]) - float64(x[i+lag])
+		return d*d < threshold
+	}
+
+	// errors made by a change at from: all the samples must be the same
+	errors := 0
+	for i := from; i < to; i++ {
+		if !same(i) {
+			errors++
+		}
+	}
+	best, bestErrors := from, errors
+	for t := from + 1; t < to; t++ {
+		if same(t - 1) {
+			errors++
+		} else {
+			errors--
+		}
+		if errors < bestErrors {
+			best, bestErrors = t, errors
+		}
+	}
+	return best
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
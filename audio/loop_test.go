@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+// noise is a random signal with the low frequencies of music, so that it
+// survives the downsampling of FindLoop.
+func noise(rng *rand.Rand, n int) []float64 {
+	x := make([]float64, n)
+	var y float64
+	for i := range x {
+		y = 0.9*y + 0.1*(rng.Float64()*2-1)
+		x[i] = y
+	}
+	return x
+}
+
+// looped makes a song of an intro, then the loop played repeat times (the
+// fraction of a loop at the end fading out), then an outro.
+func looped(intro, loop []float64, repeat float64, outro []float64) []float64 {
+	song := append([]float64{}, intro...)
+	played := int(repeat * float64(len(loop)))
+	for i := 0; i < played; i++ {
+		s := loop[i%len(loop)]
+		if i >= 2*len(loop) {
+			s *= 1 - float64(i-2*len(loop))/float64(played-2*len(loop))
+		}
+		song = append(song, s)
+	}
+	return append(song, outro...)
+}
+
+func toFloat32(x []float64) []float32 {
+	rv := make([]float32, len(x))
+	for i, s := range x {
+		rv[i] = float32(s)
+	}
+	return rv
+}
+
+func TestFindLoop(t *testing.T) {
+	const rate = 8000
+	rng := rand.New(rand.NewSource(1))
+	tests := []struct {
+		name      string
+		samples   []float64
+		wantStart int
+		wantEnd   int
+	}{
+		{"intro and two loops", looped(noise(rng, 2*rate), noise(rng, 7*rate), 2, nil), 2 * rate, 9 * rate},
+		{"fade-out", looped(noise(rng, 3*rate+123), noise(rng, 6*rate+45), 2.5, nil), 3*rate + 123, 9*rate + 168},
+		{"no intro, outro", looped(nil, noise(rng, 8*rate+7), 2, noise(rng, 3*rate)), 0, 8*rate + 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := FindLoop(toFloat32(tt.samples), rate)
+			assert.True(t, found)
+			assert.InDelta(t, tt.wantStart, got.Start, 2)
+			assert.Equal(t, tt.wantEnd-tt.wantStart, got.End-got.Start)
+			assert.Greater(t, got.Confidence, 0.95)
+		})
+	}
+}
+
+func Test_refineLag_window(t *testing.T) {
+	const rate = 8000
+	rng := rand.New(rand.NewSource(3))
+	x := toFloat32(looped(noise(rng, 2*rate), noise(rng, 7*rate), 2, noise(rng, rate)))
+	// only the last 3 seconds that have a copy one loop earlier are compared
+	assert.Equal(t, 7*rate, refineLag(x, 7*rate+5, 10, 3*rate))
+}
+
+func TestFindLoop_noLoop(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	samples := noise(rng, 20*8000)
+	got, found := FindLoop(toFloat32(samples), 8000)
+	if found {
+		assert.Less(t, got.Confidence, 0.5)
+	}
+
+	_, found = FindLoop(toFloat32(noise(rng, 3*8000)), 8000)
+	assert.False(t, found, "too short")
+}
+
+func TestLoopPoints_micro(t *testing.T) {
+	l := LoopPoints{Start: 48000, End: 44100 * 600, SampleRate: 44100}
+	assert.Equal(t, 1088435, l.StartMicro())
+	assert.Equal(t, 600000000, l.EndMicro())
+}
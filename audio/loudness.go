@@ -0,0 +1,176 @@
+package audio
+
+import (
+	"math"
+)
+
+// ReferenceLUFS is the loudness of a normalized song, the reference of
+// ReplayGain 2.0.
+const ReferenceLUFS = -18
+
+// Gain measures the gain that normalizes a file: its ReplayGain tag if it
+// has one, otherwise the difference between ReferenceLUFS and its loudness.
+func Gain(path string) (float64, error) {
+	gain, err := ReadReplayGain(path)
+	if err == nil {
+		return gain, nil
+	}
+
+	// a mono file is measured as mono: upmixed, it would be 3 dB louder
+	channels, err := Channels(path)
+	if err != nil {
+		return 0, err
+	}
+	if channels > 2 {
+		// surround channels don't have the weights of BS.1770, they are
+		// downmixed to stereo instead
+		channels = 2
+	}
+	meter := newLoudnessMeter(48000, channels)
+	if err := stream(path, 48000, channels, meter.add); err != nil {
+		return 0, err
+	}
+	loudness, ok := meter.loudness()
+	if !ok {
+		// silence can't be normalized
+		return 0, nil
+	}
+	return ReferenceLUFS - loudness, nil
+}
+
+// Loudness is the integrated loudness of the samples in LUFS, as defined by
+// ITU-R BS.1770 and EBU R128: the mean power of the K-weighted samples, over
+// blocks of 400ms that are neither silent nor much quieter than the others.
+// It returns false when all the blocks are silent.
+func Loudness(pcm PCM) (float64, bool) {
+	meter := newLoudnessMeter(pcm.SampleRate, len(pcm.Channels))
+	frame := make([]float64, len(pcm.Channels))
+	for i := 0; i < pcm.Len(); i++ {
+		for c, samples := range pcm.Channels {
+			frame[c] = samples[i]
+		}
+		meter.add(frame)
+	}
+	return meter.loudness()
+}
+
+// loudnessMeter measures the loudness of samples given frame by frame. It
+// only keeps the state of the filters and the power of each step of 100ms,
+// a quarter of a block.
+type loudnessMeter struct {
+	blockSize  int
+	step       int
+	filters    [][2]biquad
+	sum        float64
+	count      int
+	stepPowers []float64
+}
+
+func newLoudnessMeter(sampleRate, channels int) *loudnessMeter {
+	blockSize := int(0.4 * float64(sampleRate))
+	m := loudnessMeter{blockSize: blockSize, step: blockSize / 4, filters: make([][2]biquad, channels)}
+	for c := range m.filters {
+		m.filters[c] = kWeighting(sampleRate)
+	}
+	return &m
+}
+
+func (m *loudnessMeter) add(frame []float64) {
+	// mean square of the K-weighted samples of each step, summed over the
+	// channels, so that the 4 steps of a block give its power
+	for c, x := range frame {
+		y := m.filters[c][1].next(m.filters[c][0].next(x))
+		m.sum += y * y
+	}
+	m.count++
+	if m.count == m.step {
+		m.stepPowers = append(m.stepPowers, m.sum/float64(m.blockSize))
+		m.sum, m.count = 0, 0
+	}
+}
+
+func (m *loudnessMeter) loudness() (float64, bool) {
+	if len(m.stepPowers) < 4 {
+		return 0, false
+	}
+	blocks := make([]float64, 0, len(m.stepPowers))
+	for i := 0; i+4 <= len(m.stepPowers); i++ {
+		blocks = append(blocks, m.stepPowers[i]+m.stepPowers[i+1]+m.stepPowers[i+2]+m.stepPowers[i+3])
+	}
+
+	// absolute gate at -70 LUFS, then relative gate 10 LU (a tenth of the
+	// power) below the loudness of the remaining blocks
+	gated := gate(blocks, powerOf(-70))
+	if len(gated) == 0 {
+		return 0, false
+	}
+	gated = gate(gated, mean(gated)/10)
+	return loudnessOf(mean(gated)), true
+}
+
+func loudnessOf(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+func powerOf(loudness float64) float64 {
+	return math.Pow(10, (loudness+0.691)/10)
+}
+
+func gate(blocks []float64, threshold float64) []float64 {
+	kept := make([]float64, 0, len(blocks))
+	for _, b := range blocks {
+		if b > threshold {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// kWeighting is the K-weighting filter of BS.1770: a high shelf that models
+// the head, then a high-pass filter. The coefficients are computed for the
+// sample rate, as libebur128 does.
+func kWeighting(sampleRate int) [2]biquad {
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / float64(sampleRate))
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b: [3]float64{(vh + vb*k/q + k*k) / a0, 2 * (k*k - vh) / a0, (vh - vb*k/q + k*k) / a0},
+		a: [3]float64{1, 2 * (k*k - 1) / a0, (1 - k/q + k*k) / a0},
+	}
+
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / float64(sampleRate))
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b: [3]float64{1, -2, 1},
+		a: [3]float64{1, 2 * (k*k - 1) / a0, (1 - k/q + k*k) / a0},
+	}
+
+	return [2]biquad{shelf, highPass}
+}
+
+// biquad is a filter, with the last samples it was given and returned.
+type biquad struct {
+	b, a           [3]float64
+	x1, x2, y1, y2 float64
+}
+
+func (f *biquad) next(x float64) float64 {
+	y := f.b[0]*x + f.b[1]*f.x1 + f.b[2]*f.x2 - f.a[1]*f.y1 - f.a[2]*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
@@ -0,0 +1,91 @@
+package audio
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sine makes a stereo 1 kHz sine, with a peak at the given dBFS.
+func sine(sampleRate int, seconds float64, dbfs float64) []float64 {
+	amplitude := math.Pow(10, dbfs/20)
+	samples := make([]float64, int(seconds*float64(sampleRate)))
+	for i := range samples {
+		samples[i] = amplitude * math.Sin(2*math.Pi*1000*float64(i)/float64(sampleRate))
+	}
+	return samples
+}
+
+func stereo(samples []float64, sampleRate int) PCM {
+	return PCM{SampleRate: sampleRate, Channels: [][]float64{samples, samples}}
+}
+
+func TestLoudness(t *testing.T) {
+	// the test signals of EBU Tech 3341
+	tests := []struct {
+		name       string
+		sampleRate int
+		samples    []float64
+		want       float64
+	}{
+		{"-23 dBFS", 48000, sine(48000, 20, -23), -23},
+		{"-33 dBFS", 48000, sine(48000, 20, -33), -33},
+		{"44.1 kHz", 44100, sine(44100, 20, -23), -23},
+		{"relative gate", 48000, append(append(sine(48000, 10, -36), sine(48000, 60, -23)...), sine(48000, 10, -36)...), -23},
+		{"absolute gate", 48000, append(append(sine(48000, 10, -72), sine(48000, 20, -20)...), make([]float64, 48000*10)...), -20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Loudness(stereo(tt.samples, tt.sampleRate))
+			assert.True(t, ok)
+			assert.InDelta(t, tt.want, got, 0.1)
+		})
+	}
+}
+
+func TestLoudness_mono(t *testing.T) {
+	// a mono channel counts once, the same signal in stereo twice
+	samples := sine(48000, 20, -23)
+	mono, ok := Loudness(PCM{SampleRate: 48000, Channels: [][]float64{samples}})
+	assert.True(t, ok)
+	stereo, _ := Loudness(stereo(samples, 48000))
+	assert.InDelta(t, stereo-3.01, mono, 0.01)
+}
+
+func TestLoudness_silence(t *testing.T) {
+	_, ok := Loudness(stereo(make([]float64, 48000), 48000))
+	assert.False(t, ok)
+	_, ok = Loudness(stereo(make([]float64, 10), 48000))
+	assert.False(t, ok)
+}
+
+func Test_readFloat32LE(t *testing.T) {
+	// 0.5 and -1, then 0.25 and 0, then a partial frame
+	data := []byte{0, 0, 0, 0x3f, 0, 0, 0x80, 0xbf, 0, 0, 0x80, 0x3e, 0, 0, 0, 0, 0xff}
+	var got [][]float64
+	err := readFloat32LE(bytes.NewReader(data), 2, func(frame []float64) {
+		got = append(got, append([]float64{}, frame...))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]float64{{0.5, -1}, {0.25, 0}}, got)
+}
+
+func TestDecode(t *testing.T) {
+	dir := t.TempDir()
+	defer func(ffmpeg string) { FFmpeg = ffmpeg }(FFmpeg)
+
+	// a fake ffmpeg that writes 0.5 and -1, then 0.25 and 0
+	FFmpeg = filepath.Join(dir, "ffmpeg")
+	script := "#!/bin/sh\nprintf '\\000\\000\\000\\077\\000\\000\\200\\277\\000\\000\\200\\076\\000\\000\\000\\000'\n"
+	assert.NoError(t, os.WriteFile(FFmpeg, []byte(script), 0700))
+	pcm, err := Decode("a.brstm", 8000, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, PCM{SampleRate: 8000, Channels: [][]float64{{0.5, 0.25}, {-1, 0}}}, pcm)
+
+	assert.NoError(t, os.WriteFile(FFmpeg, []byte("#!/bin/sh\necho unknown format >&2\nexit 1\n"), 0700))
+	_, err = Decode("a.brstm", 8000, 2)
+	assert.ErrorContains(t, err, "unknown format")
+}
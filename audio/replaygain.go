@@ -0,0 +1,86 @@
+package audio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrNoReplayGain is returned by ReadReplayGain when a file has no
+// ReplayGain tag.
+var ErrNoReplayGain = errors.New("no ReplayGain tag")
+
+// ReadReplayGain reads the REPLAYGAIN_TRACK_GAIN tag of a file with ffprobe,
+// in dB. It returns ErrNoReplayGain when the file has no such tag.
+func ReadReplayGain(path string) (float64, error) {
+	out, err := exec.Command(FFprobe, "-v", "error", "-show_entries", "format_tags:stream_tags", "-of", "json", path).Output()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return replayGainFromProbe(out)
+}
+
+// Channels reads the number of channels of the audio of a file with ffprobe.
+func Channels(path string) (int, error) {
+	out, err := exec.Command(FFprobe, "-v", "error", "-select_streams", "a:0", "-show_entries", "stream=channels", "-of", "csv=p=0", path).Output()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	channels, err := parseChannels(out)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return channels, nil
+}
+
+func parseChannels(out []byte) (int, error) {
+	channels, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || channels < 1 {
+		return 0, fmt.Errorf("invalid number of channels %q", strings.TrimSpace(string(out)))
+	}
+	return channels, nil
+}
+
+// probeTags is the output of ffprobe -show_entries format_tags:stream_tags.
+// The tags of OGG files are on the stream, the others on the format.
+type probeTags struct {
+	Streams []struct {
+		Tags map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		Tags map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+func replayGainFromProbe(out []byte) (float64, error) {
+	var probe probeTags
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return 0, err
+	}
+	all := []map[string]string{probe.Format.Tags}
+	for _, stream := range probe.Streams {
+		all = append(all, stream.Tags)
+	}
+	for _, tags := range all {
+		for name, value := range tags {
+			if strings.EqualFold(name, "REPLAYGAIN_TRACK_GAIN") {
+				return parseReplayGain(value)
+			}
+		}
+	}
+	return 0, ErrNoReplayGain
+}
+
+// parseReplayGain parses a gain like "-6.54 dB".
+func parseReplayGain(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(value, "dB"), "DB"))
+	gain, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ReplayGain %q", value)
+	}
+	return gain, nil
+}
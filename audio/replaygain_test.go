@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_replayGainFromProbe(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    float64
+		wantErr error
+	}{
+		{"format tag", `{"streams":[{}],"format":{"tags":{"REPLAYGAIN_TRACK_GAIN":"-6.54 dB"}}}`, -6.54, nil},
+		{"stream tag, lower case", `{"streams":[{"tags":{"replaygain_track_gain":"+2.10 dB"}}],"format":{}}`, 2.1, nil},
+		{"no tag", `{"streams":[{"tags":{"title":"A"}}],"format":{"tags":{"REPLAYGAIN_ALBUM_GAIN":"-1 dB"}}}`, 0, ErrNoReplayGain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := replayGainFromProbe([]byte(tt.out))
+			assert.Equal(t, tt.wantErr, err)
+			assert.InDelta(t, tt.want, got, 0.0001)
+		})
+	}
+}
+
+func Test_parseChannels(t *testing.T) {
+	got, err := parseChannels([]byte("1\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, got)
+
+	_, err = parseChannels([]byte(""))
+	assert.Error(t, err)
+	_, err = parseChannels([]byte("0\n"))
+	assert.Error(t, err)
+}
+
+func Test_parseReplayGain(t *testing.T) {
+	got, err := parseReplayGain(" -0.5dB")
+	assert.NoError(t, err)
+	assert.Equal(t, -0.5, got)
+
+	_, err = parseReplayGain("loud")
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"vgsgo/songrep"
+)
+
+// runCheckLoop plays the loop boundary of a song, so that its loop points
+// can be checked and moved, then saved to its metadata file.
+func runCheckLoop(arguments []string) {
+	fs := flag.NewFlagSet("check-loop", flag.ExitOnError)
+	seconds := fs.Float64("seconds", 3, "seconds played before the loop end and after the loop start")
+	repeat := fs.Int("repeat", 2, "number of times the loop boundary is played")
+	step := fs.Int("step", 10, "milliseconds the loop points are moved by, without a number")
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() < 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide the path of the song (or search terms) and one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	song, found := findSong(songrep.SongsFromFiles(fs.Args()[1:]), fs.Arg(0))
+	if !found {
+		return
+	}
+
+	player := makePlayer(1, 0, false)
+	for {
+		fmt.Printf("%s: loop from %dµs to %dµs\n", song.Path, song.LoopStartMicro, song.LoopEndMicro)
+		player.PlayLoopBoundary(song, float32(*seconds), *repeat)
+
+		action := player.AskLoopAction(*step)
+		song = action.Apply(song)
+		if action.Save {
+			songrep.UpdateSongMetadata(song.MetadataFile, map[string]map[string]interface{}{
+				song.Path: {"loop_start": song.LoopStartMicro, "loop_end": song.LoopEndMicro},
+			})
+			fmt.Printf("saved to %s\n", song.MetadataFile)
+		}
+		if action.Quit || action.Save {
+			return
+		}
+	}
+}
+
+// findSong finds the song with this path, or asks to choose among the songs
+// matching the search terms.
+func findSong(songs []songrep.Song, pathOrTerms string) (songrep.Song, bool) {
+	for _, song := range songs {
+		if song.Path == pathOrTerms {
+			return song, true
+		}
+	}
+
+	results := songrep.Search(songs, pathOrTerms)
+	switch len(results) {
+	case 0:
+		fmt.Println("no song found")
+		return songrep.Song{}, false
+	case 1:
+		return results[0].Song, true
+	}
+	if len(results) > 20 {
+		results = results[:20]
+	}
+	for i, result := range results {
+		fmt.Printf("%3d. %s\n", i+1, result.Song.Path)
+	}
+	return chooseResult(results)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"vgsgo/audio"
+	"vgsgo/songrep"
+)
+
+// detectSampleRate is the sample rate at which the songs are decoded, in
+// mono, to find their loop, precise to 23µs.
+const detectSampleRate = 44100
+
+// runDetectLoop finds the loop points of the songs without loop points, from
+// their audio, and offers to write them to the metadata files.
+func runDetectLoop(arguments []string) {
+	fs := flag.NewFlagSet("detect-loop", flag.ExitOnError)
+	all := fs.Bool("all", false, "also analyze the songs that have loop points")
+	minConfidence := fs.Float64("min-confidence", 0.9, "minimum confidence of the loops offered to be written")
+	write := fs.Bool("write", false, "write the loops with enough confidence without asking")
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for _, song := range songrep.SongsFromFiles(fs.Args()) {
+		if song.HasLoopPoints() && !*all {
+			continue
+		}
+		samples, err := audio.DecodeMono(song.AbsPath, detectSampleRate)
+		if err != nil {
+			fmt.Printf("%s: %v\n", song.Path, err)
+			continue
+		}
+		loop, found := audio.FindLoop(samples, detectSampleRate)
+		if !found {
+			fmt.Printf("%s: no loop found\n", song.Path)
+			continue
+		}
+
+		fmt.Printf("%s: loop from %dµs to %dµs, confidence %.2f", song.Path, loop.StartMicro(), loop.EndMicro(), loop.Confidence)
+		if song.HasLoopPoints() {
+			fmt.Printf(" (metadata: %dµs to %dµs)", song.LoopStartMicro, song.LoopEndMicro)
+		}
+		fmt.Println()
+		if loop.Confidence < *minConfidence {
+			continue
+		}
+
+		if !*write {
+			fmt.Print("Write it to the metadata file (y/N)? ")
+			if !scanner.Scan() {
+				return
+			}
+			if !strings.EqualFold(strings.TrimSpace(scanner.Text()), "y") {
+				continue
+			}
+		}
+		songrep.UpdateSongMetadata(song.MetadataFile, map[string]map[string]interface{}{
+			song.Path: {"loop_start": loop.StartMicro(), "loop_end": loop.EndMicro()},
+		})
+	}
+}
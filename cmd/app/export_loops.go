@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"vgsgo/formats"
+	"vgsgo/ogg"
+	"vgsgo/songrep"
+)
+
+var loopFormats = []string{"ogg", "txt", "json"}
+
+// runExportLoops writes the loop points of the metadata files in formats
+// other players understand.
+func runExportLoops(arguments []string) {
+	fs := flag.NewFlagSet("export-loops", flag.ExitOnError)
+	formatList := fs.String("format", "txt,json", "comma-separated formats: ogg (LOOPSTART/LOOPLENGTH comments of the .ogg files), txt and json (sidecar files)")
+	sampleRate := fs.Int("sample-rate", 0, "sample rate of the files whose header can't be read (see scan), to convert the loop points to samples")
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+	enabled := make(map[string]bool)
+	for _, format := range strings.Split(*formatList, ",") {
+		if !contains(loopFormats, format) {
+			fmt.Printf("unknown format %q (must be ogg, txt or json)\n", format)
+			os.Exit(1)
+		}
+		enabled[format] = true
+	}
+
+	exported := 0
+	for _, song := range songrep.SongsFromFiles(fs.Args()) {
+		if !song.HasLoopPoints() {
+			continue
+		}
+		isOgg := strings.EqualFold(filepath.Ext(song.AbsPath), ".ogg")
+		rate := *sampleRate
+		var data []byte
+		var info ogg.VorbisInfo
+		if isOgg {
+			var err error
+			if data, err = os.ReadFile(song.AbsPath); err == nil {
+				info, err = ogg.ReadVorbis(data)
+			}
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%s: %v, skipped\n", song.AbsPath, err)
+				continue
+			}
+			rate = info.SampleRate
+		} else if header, err := formats.ReadFile(song.AbsPath); err == nil {
+			rate = header.SampleRate
+		}
+		if rate == 0 {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: unknown sample rate (see -sample-rate), skipped\n", song.AbsPath)
+			continue
+		}
+
+		sidecar := songrep.MakeLoopSidecar(song, rate)
+		if enabled["ogg"] && isOgg {
+			if err := writeOggLoop(song.AbsPath, data, info.Comments, sidecar); err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "%s: %v, skipped\n", song.AbsPath, err)
+				continue
+			}
+		}
+		base := strings.TrimSuffix(song.AbsPath, filepath.Ext(song.AbsPath)) + ".loop"
+		if enabled["txt"] {
+			fh := createFile(base + ".txt")
+			sidecar.WriteText(fh)
+			_ = fh.Close()
+		}
+		if enabled["json"] {
+			content, err := json.MarshalIndent(sidecar, "", "  ")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			fh := createFile(base + ".json")
+			if _, err := fh.Write(content); err != nil {
+				log.Fatalln(err)
+			}
+			_ = fh.Close()
+		}
+		exported++
+	}
+	fmt.Printf("loop points of %d songs exported\n", exported)
+}
+
+// writeOggLoop sets the LOOPSTART and LOOPLENGTH comments of an ogg file,
+// through a temporary file so that the file is never left half written.
+// The file keeps its permissions.
+func writeOggLoop(path string, data []byte, comments ogg.VorbisComments, sidecar songrep.LoopSidecar) error {
+	comments.Set("LOOPSTART", strconv.Itoa(sidecar.LoopStart))
+	comments.Set("LOOPLENGTH", strconv.Itoa(sidecar.LoopLength))
+	written, err := ogg.WriteVorbisComments(data, comments)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, written, stat.Mode().Perm())
+	if err == nil {
+		// WriteFile applies the umask
+		err = os.Chmod(tmp, stat.Mode().Perm())
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func createFile(path string) *os.File {
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return fh
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -13,50 +13,175 @@ import (
 	"vgsgo/songrep"
 )
 
+// commands are the subcommands, called with the arguments following the
+// name of the command. Without a subcommand, songs are played.
+var commands = map[string]func(args []string){
+	"check-loop":   runCheckLoop,
+	"detect-loop":  runDetectLoop,
+	"export-loops": runExportLoops,
+	"playlist":     runPlaylist,
+	"scan":         runScan,
+	"search":       runSearch,
+	"stats":        runStats,
+	"validate":     runValidate,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, found := commands[os.Args[1]]; found {
+			command(os.Args[2:])
+			return
+		}
+	}
 
 	args := getArgs()
 
-	player := playerpck.Player{
-		Cmd:            "/usr/bin/mplayer",
-		Input:          os.Stdin,
-		Output:         os.Stdout,
-		MaxPlays:       args.maxPlays,
-		MaxPlayTimeSec: args.maxPlayTime,
-		ContinuousPlay: args.continuousPlay,
-	}
+	player := makePlayer(args.maxPlays, args.maxPlayTime, args.continuousPlay)
+	player.CheckMetadata = args.checkMetadata
+	player.FadeInSec = float32(args.fadeInSec)
+	player.FadeOutSec = float32(args.fadeOutSec)
+	player.CrossfadeSec = float32(args.crossfadeSec)
+	player.Normalize = args.normalize
+	player.Outro = args.outro
+	checkCrossfade(player, args.controls)
 
 	filters := songrep.Filters{
 		MinRating:         float32(args.minRating),
+		MaxRating:         float32(args.maxRating),
 		OnlyHasRating:     args.onlyHasRating,
 		OnlyHasNoRating:   args.onlyHasNoRating,
 		MinDurationSec:    args.minDurationSec,
+		MaxDurationSec:    args.maxDurationSec,
+		MinPlays:          args.minPlayCount,
+		PlayedSince:       args.playedSince,
+		NotPlayedSince:    args.notPlayedSince,
 		TitleContains:     args.titleContains,
 		GameTitleContains: args.gameTitleContains,
+		SkipLimit:         args.skipLimit,
+		MinGameRating:     float32(args.minGameRating),
+		ExcludeGames:      args.excludeGames,
+		ComposerContains:  args.composerContains,
+		Platform:          args.platform,
+		MinYear:           args.minYear,
+		MaxYear:           args.maxYear,
+		Tags:              args.tags,
+		Query:             args.query,
+		TitleMatch:        args.titleMatch,
+		GameTitleMatch:    args.gameTitleMatch,
+		ComposerMatch:     args.composerMatch,
+	}
+	if args.maxPlayCount >= 0 {
+		filters.MaxPlays = &args.maxPlayCount
+	}
+	selection := songrep.Selection{Strategy: args.strategy, Sort: args.sort}
+
+	if args.saveSmart != "" {
+		saveSmartPlaylist(args.ratings, songrep.SmartPlaylist{Name: args.saveSmart, Filters: filters, Selection: selection})
+	}
+	if args.smart != "" {
+		smart := getSmartPlaylist(args.ratings, args.smart)
+		filters = smart.Filters
+		selection = smart.Selection
+	}
+
+	if err := filters.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	// the server doesn't evaluate queries, also those of smart playlists
+	if filters.Query != nil && strings.HasPrefix(args.dbFiles[0], "http") {
+		fmt.Println("-query can only be used with local db files")
+		os.Exit(1)
 	}
 
-	conf := getConfiguration(args)
-	run(conf.songRep, conf.ratingRep, player, filters, args.ratings)
+	if args.exportFile != "" {
+		exportSongs(args, filters, selection)
+		return
+	}
+
+	conf := getConfiguration(args, selection)
+	// the controls read the standard input, after the remote configuration
+	// has asked for the password
+	if args.controls {
+		player.Controls = playerpck.ReadControls(os.Stdin)
+		player.Input = player.Controls
+	}
+	session := playerpck.Session{MaxSongs: args.sessionSongs, MaxTime: args.sessionTime, Start: time.Now()}
+	run(conf.songRep, conf.ratingRep, player, filters, &session)
 
 }
 
-func run(songRep songrep.SongRepository, ratingRep songrep.RatingRepository, player playerpck.Player, filters songrep.Filters, ratingFile string) {
+func makePlayer(maxPlays, maxPlayTime int, continuousPlay bool) playerpck.Player {
+	return playerpck.Player{
+		Cmd:            "/usr/bin/mplayer",
+		Input:          os.Stdin,
+		Output:         os.Stdout,
+		MaxPlays:       maxPlays,
+		MaxPlayTimeSec: maxPlayTime,
+		ContinuousPlay: continuousPlay,
+	}
+}
+
+// checkCrossfade exits when the songs can't be crossfaded: they must be
+// played continuously, and they must end.
+func checkCrossfade(player playerpck.Player, controls bool) {
+	if player.CrossfadeSec == 0 {
+		return
+	}
+	if controls {
+		fmt.Println("-crossfade can't be used with -controls")
+		os.Exit(1)
+	}
+	if !player.ContinuousPlay || (player.MaxPlays == 0 && player.MaxPlayTimeSec == 0) {
+		fmt.Println("-crossfade needs -continuous, and -max-plays or -max-play-time")
+		os.Exit(1)
+	}
+}
+
+// run plays the songs until there is no more song, the user quits or the
+// session is over, then saves the ratings.
+func run(songRep songrep.SongRepository, ratingRep songrep.RatingRepository, player playerpck.Player, filters songrep.Filters, session *playerpck.Session) {
+	defer ratingRep.Save()
+	if player.ContinuousPlay && player.CrossfadeSec != 0 {
+		runCrossfaded(songRep, ratingRep, player, filters, session)
+		return
+	}
 	for {
+		if session.Over(time.Now()) {
+			fmt.Println("end of the session")
+			return
+		}
 		song, found := songRep.GetRandomSong(filters)
 		if !found {
 			fmt.Println("no more song")
 			return
 		}
-		player.Play(song)
+		session.AddSong()
+		report := session.Fit(player, song, time.Now()).Play(song)
+		if report.End == playerpck.EndError {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: the player failed, skipped\n", song.Path)
+			continue
+		}
+		play := songrep.Play{
+			ListenedSec: report.ListenedSec,
+			Loops:       report.Loops,
+			Skipped:     report.Skipped,
+		}
 
-		if player.ContinuousPlay {
-			ratingRep.AddPlay(song, int(time.Now().Unix()), 0)
+		// a song skipped or quit with the controls is not rated
+		if player.ContinuousPlay || report.End != playerpck.EndFinished {
+			play.Timestamp = int(time.Now().Unix())
+			ratingRep.AddPlay(song, play)
+			if report.End == playerpck.EndQuit {
+				return
+			}
 		} else {
 			actions := player.Rate()
-			rating := actions.Value
-			ratingRep.AddPlay(song, int(time.Now().Unix()), rating)
-			if actions.Resume {
-				player.PlayIndefinitely(song)
+			play.Timestamp = int(time.Now().Unix())
+			play.Rating = actions.Value
+			ratingRep.AddPlay(song, play)
+			if actions.Resume && player.PlayIndefinitely(song) == playerpck.EndQuit {
+				return
 			}
 
 			if actions.Quit {
@@ -67,6 +192,47 @@ func run(songRep songrep.SongRepository, ratingRep songrep.RatingRepository, pla
 	}
 }
 
+// runCrossfaded plays the songs continuously, each song starting while the
+// previous one fades out.
+func runCrossfaded(songRep songrep.SongRepository, ratingRep songrep.RatingRepository, player playerpck.Player, filters songrep.Filters, session *playerpck.Session) {
+	song, found := songRep.GetRandomSong(filters)
+	if !found {
+		fmt.Println("no more song")
+		return
+	}
+	session.AddSong()
+	current := session.Fit(player, song, time.Now()).Start(song)
+	for {
+		current.WaitCrossfade()
+		var next *playerpck.Playback
+		if session.Over(time.Now()) {
+			fmt.Println("end of the session")
+		} else if song, found := songRep.GetRandomSong(filters); found {
+			session.AddSong()
+			next = session.Fit(player, song, time.Now()).Start(song)
+		} else {
+			fmt.Println("no more song")
+		}
+
+		report := current.Wait()
+		if report.End == playerpck.EndError {
+			_, _ = fmt.Fprintf(os.Stderr, "%s: the player failed, skipped\n", current.Song.Path)
+		} else {
+			ratingRep.AddPlay(current.Song, songrep.Play{
+				Timestamp:   int(time.Now().Unix()),
+				ListenedSec: report.ListenedSec,
+				Loops:       report.Loops,
+				Skipped:     report.Skipped,
+			})
+		}
+
+		if next == nil {
+			return
+		}
+		current = next
+	}
+}
+
 type Arguments struct {
 	dbFiles           []string
 	ratings           string
@@ -75,11 +241,60 @@ type Arguments struct {
 	continuousPlay    bool
 	playLast          bool
 	minRating         float64
+	maxRating         float64
 	onlyHasRating     bool
 	onlyHasNoRating   bool
 	minDurationSec    int
+	maxDurationSec    int
+	minPlayCount      int
+	maxPlayCount      int
+	playedSince       int
+	notPlayedSince    int
 	titleContains     string
 	gameTitleContains string
+	skipLimit         int
+	minGameRating     float64
+	excludeGames      stringList
+	composerContains  string
+	platform          string
+	minYear           int
+	maxYear           int
+	tags              stringList
+	query             *songrep.Query
+	titleMatch        songrep.MatchMode
+	gameTitleMatch    songrep.MatchMode
+	composerMatch     songrep.MatchMode
+	ratingMode        string
+	ratingHalfLife    float64
+	ratingPriorWeight float64
+	strategy          songrep.Strategy
+	sort              songrep.SortOrder
+	smart             string
+	saveSmart         string
+	checkMetadata     bool
+	fadeInSec         float64
+	fadeOutSec        float64
+	crossfadeSec      float64
+	normalize         playerpck.Normalization
+	outro             bool
+	controls          bool
+	exportFile        string
+	importFile        string
+	album             string
+	sessionTime       time.Duration
+	sessionSongs      int
+}
+
+// stringList is a flag that can be repeated.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
 }
 
 func getArgs() Arguments {
@@ -89,13 +304,50 @@ func getArgs() Arguments {
 	flag.IntVar(&args.maxPlays, "max-plays", 0, "maximum number of plays (default is 0, infinity)")
 	flag.IntVar(&args.maxPlayTime, "max-play-time", 0, "maximum time to play (default is 0, infinity)")
 	flag.BoolVar(&args.continuousPlay, "continuous", false, "don't stop to ask rating")
+	flag.BoolVar(&args.controls, "controls", false, "control the songs with commands followed by Enter (p: pause, f/b [SEC]: seek, s: skip, q: quit) instead of the keys of mplayer")
+	flag.DurationVar(&args.sessionTime, "session-time", 0, "stop after this listening time, like 45m, the last song fading out (default is 0, no limit)")
+	flag.IntVar(&args.sessionSongs, "session-songs", 0, "stop after this number of songs (default is 0, no limit)")
 	flag.BoolVar(&args.playLast, "play-last", false, "don't shuffle songs, play the last ones")
 	flag.Float64Var(&args.minRating, "min-rating", 0, "minimum rating. Add --only-has-rating to limit to songs that have ratings")
 	flag.BoolVar(&args.onlyHasRating, "only-has-rating", false, "limit to songs that have a rating")
 	flag.BoolVar(&args.onlyHasNoRating, "only-has-no-rating", false, "limit to songs that don't have a rating")
+	flag.Float64Var(&args.maxRating, "max-rating", 0, "maximum rating (songs without rating are kept, unless -only-has-rating)")
 	flag.IntVar(&args.minDurationSec, "min-duration", 0, "minimum duration")
+	flag.IntVar(&args.maxDurationSec, "max-duration", 0, "maximum duration")
+	flag.IntVar(&args.minPlayCount, "min-play-count", 0, "limit to songs played at least that many times")
+	flag.IntVar(&args.maxPlayCount, "max-play-count", -1, "limit to songs played at most that many times, 0 for the songs never played (default is -1, no limit)")
+	playedSince := flag.String("played-since", "", "limit to songs played since this date (YYYY-MM-DD)")
+	notPlayedSince := flag.String("not-played-since", "", "limit to songs not played since this date (YYYY-MM-DD)")
 	flag.StringVar(&args.titleContains, "title", "", "limit to song with a title that contains the string")
 	flag.StringVar(&args.gameTitleContains, "game-title", "", "limit to song with a game title that contains the string")
+	flag.IntVar(&args.skipLimit, "skip-limit", 0, "exclude songs skipped that many times in a row (default is 0, no limit)")
+	flag.Float64Var(&args.minGameRating, "min-game-rating", 0, "minimum rating of the game (its own rating, or the mean rating of its songs)")
+	flag.Var(&args.excludeGames, "exclude-game", "exclude the songs of the game with this title (can be repeated)")
+	flag.StringVar(&args.composerContains, "composer", "", "limit to song with a composer (of the song or of the game) that contains the string")
+	flag.StringVar(&args.platform, "platform", "", "limit to games of this platform")
+	flag.IntVar(&args.minYear, "min-year", 0, "limit to games released this year or after")
+	flag.IntVar(&args.maxYear, "max-year", 0, "limit to games released this year or before")
+	flag.Var(&args.tags, "tag", "limit to song with this tag (can be repeated, songs must have all the tags)")
+	flag.TextVar(&args.titleMatch, "title-match", songrep.MatchInsensitive, "how -title is matched: insensitive, normalized (also ignores accents), regexp or exact")
+	flag.TextVar(&args.gameTitleMatch, "game-title-match", songrep.MatchInsensitive, "how -game-title is matched: insensitive, normalized (also ignores accents), regexp or exact")
+	flag.TextVar(&args.composerMatch, "composer-match", songrep.MatchInsensitive, "how -composer is matched: insensitive, normalized (also ignores accents), regexp or exact")
+	queryString := flag.String("query", "", "limit to songs matching the query, like: game:zelda AND (tag:battle OR rating>=4)")
+	flag.TextVar(&args.strategy, "strategy", songrep.StrategyShuffle, "how the next song is chosen: shuffle, ordered (see -sort) or weighted (by rating)")
+	flag.TextVar(&args.sort, "sort", songrep.SortOrder{}, "order of the songs for -strategy ordered: title, game, year, duration, rating, plays or last-played, prefixed with - for a descending order")
+	flag.StringVar(&args.smart, "smart", "", "play the smart playlist with this name, instead of the filters of the command line")
+	flag.StringVar(&args.saveSmart, "save-smart", "", "save the filters, -strategy and -sort of the command line as a smart playlist with this name")
+	flag.Float64Var(&args.fadeInSec, "fade-in", 0, "duration in seconds of the fade-in at the start of the songs")
+	flag.Float64Var(&args.fadeOutSec, "fade-out", 0, "duration in seconds of the fade-out at the end of the last loop (with -max-plays or -max-play-time)")
+	flag.Float64Var(&args.crossfadeSec, "crossfade", 0, "duration in seconds of the crossfade between songs (with -continuous, and -max-plays or -max-play-time)")
+	flag.BoolVar(&args.outro, "outro", false, "play the end of the songs after their loop end, at the end of the last loop (with -max-plays or -max-play-time)")
+	flag.TextVar(&args.normalize, "normalize", playerpck.NormalizeOff, "normalize the loudness of the songs, with the gains measured by scan -loudness: track, game or off")
+	flag.BoolVar(&args.checkMetadata, "check-metadata", false, "warn when the duration or the loop points of a song differ from the header of its file")
+	flag.StringVar(&args.exportFile, "export", "", "write the songs matching the filters to this playlist file (.m3u8, .m3u or .pls) instead of playing them")
+	flag.StringVar(&args.album, "album", "", "play the songs of the game with this title, in the order of the soundtrack")
+	flag.StringVar(&args.importFile, "import", "", "play the songs of this playlist file (.m3u8, .m3u or .pls), in order")
+	flag.StringVar(&args.ratingMode, "rating-mode", "mean", "how ratings of a song are combined: mean, decay, bayes or median")
+	flag.Float64Var(&args.ratingHalfLife, "rating-half-life", 365, "half-life in days of a rating, for -rating-mode decay")
+	flag.Float64Var(&args.ratingPriorWeight, "rating-prior-weight", 5, "weight of the global mean rating, for -rating-mode bayes")
 
 	flag.Parse()
 
@@ -105,46 +357,122 @@ func getArgs() Arguments {
 		os.Exit(1)
 	}
 
+	if *queryString != "" {
+		query, err := songrep.ParseQuery(*queryString)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		args.query = query
+	}
+
+	args.playedSince = parseDate("-played-since", *playedSince)
+	args.notPlayedSince = parseDate("-not-played-since", *notPlayedSince)
+
+	switch args.ratingMode {
+	case "mean", "decay", "bayes", "median":
+	default:
+		fmt.Println("-rating-mode must be one of mean, decay, bayes or median")
+		os.Exit(1)
+	}
+
 	if args.maxPlays != 0 && args.maxPlayTime != 0 {
 		fmt.Println("You can't use -max-plays and -max-play-time at the same time")
 		os.Exit(1)
 	}
+	// the smart playlists are stored next to the rating file
+	if (args.smart != "" || args.saveSmart != "") && args.ratings == "" {
+		fmt.Println("-smart and -save-smart need -rating-file")
+		os.Exit(1)
+	}
 
 	args.dbFiles = flag.Args()
+	if (args.exportFile != "" || args.importFile != "" || args.album != "") && strings.HasPrefix(args.dbFiles[0], "http") {
+		fmt.Println("-export, -import and -album can only be used with local db files")
+		os.Exit(1)
+	}
+	if args.importFile != "" && args.album != "" {
+		fmt.Println("You can't use -import and -album at the same time")
+		os.Exit(1)
+	}
 	return args
 }
 
+// parseDate converts a YYYY-MM-DD date of a flag to the unix timestamp of
+// its midnight, local time. An empty date is 0.
+func parseDate(name, value string) int {
+	if value == "" {
+		return 0
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		fmt.Println(name, "must be a date like 2023-01-31")
+		os.Exit(1)
+	}
+	return int(date.Unix())
+}
+
 type AppConfiguration struct {
 	ratingRep songrep.RatingRepository
 	songRep   songrep.SongRepository
 }
 
-func getConfiguration(args Arguments) AppConfiguration {
+func getConfiguration(args Arguments, selection songrep.Selection) AppConfiguration {
 	if len(args.dbFiles) == 1 && strings.HasPrefix(args.dbFiles[0], "http") {
 		return getRemoteConfiguration(args)
 	} else {
-		return getLocalConfiguration(args)
+		return getLocalConfiguration(args, selection)
 	}
 }
 
-func getLocalConfiguration(args Arguments) AppConfiguration {
-	var ratingRep songrep.InMemoryRatingRepository
-	if _, err := os.Stat(args.ratings); err == nil {
-		fh, err := os.Open(args.ratings)
+func loadRatings(file string) songrep.InMemoryRatingRepository {
+	if _, err := os.Stat(file); err == nil {
+		fh, err := os.Open(file)
 		if err != nil {
 			log.Fatal(err)
 		}
 		rep := songrep.RatingsFromJSON(fh)
-		rep.File = args.ratings
+		rep.File = file
 		_ = fh.Close()
-		ratingRep = rep
+		return rep
 	} else {
-		ratingRep = songrep.InMemoryRatingRepository{File: args.ratings}
+		return songrep.InMemoryRatingRepository{File: file}
 	}
+}
+
+func getLocalConfiguration(args Arguments, selection songrep.Selection) AppConfiguration {
+	ratingRep := loadRatings(args.ratings)
+	ratingRep.Aggregator = getRatingAggregator(args, &ratingRep)
 
 	songRep := songrep.InMemorySongRepository{
 		Songs:            songrep.SongsFromFiles(args.dbFiles),
 		RatingRepository: ratingRep,
+		Selection:        selection,
+	}
+	warnProblems(songRep.Songs)
+
+	if args.importFile != "" {
+		songs, missing := songrep.SongsFromPlaylistEntries(readPlaylistFile(args.importFile), songRep.Songs)
+		for _, path := range missing {
+			_, _ = fmt.Fprintf(os.Stderr, "%s not found in the db files, skipped\n", path)
+		}
+		songRep.Songs = songs
+		return AppConfiguration{
+			ratingRep: &ratingRep,
+			songRep:   &songrep.PlaylistSongRepository{InMemorySongRepository: songRep},
+		}
+	}
+
+	if args.album != "" {
+		songRep.Songs = songrep.AlbumSongs(songRep.Songs, args.album)
+		if len(songRep.Songs) == 0 {
+			fmt.Printf("no song of the game %q\n", args.album)
+			os.Exit(1)
+		}
+		return AppConfiguration{
+			ratingRep: &ratingRep,
+			songRep:   &songrep.PlaylistSongRepository{InMemorySongRepository: songRep},
+		}
 	}
 
 	return AppConfiguration{
@@ -153,6 +481,20 @@ func getLocalConfiguration(args Arguments) AppConfiguration {
 	}
 }
 
+func getRatingAggregator(args Arguments, ratingRep *songrep.InMemoryRatingRepository) songrep.RatingAggregator {
+	switch args.ratingMode {
+	case "decay":
+		return songrep.DecayedMeanAggregator{HalfLifeSec: int(args.ratingHalfLife * 24 * 60 * 60)}
+	case "bayes":
+		mean, _ := ratingRep.GlobalMean()
+		return songrep.BayesianAggregator{PriorMean: mean, PriorWeight: float32(args.ratingPriorWeight)}
+	case "median":
+		return songrep.MedianAggregator{}
+	default:
+		return songrep.MeanAggregator{}
+	}
+}
+
 func getRemoteConfiguration(args Arguments) AppConfiguration {
 	var username, password string
 	s := bufio.NewScanner(os.Stdin)
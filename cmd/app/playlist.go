@@ -0,0 +1,196 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+	playerpck "vgsgo/player"
+	"vgsgo/songrep"
+)
+
+// playlistFileName is the file where the playlists are stored, in the
+// directory of the rating file.
+const playlistFileName = "playlists.json"
+
+type playlistArguments struct {
+	ratings        string
+	shuffle        bool
+	continuousPlay bool
+	maxPlays       int
+	maxPlayTime    int
+	fadeInSec      float64
+	fadeOutSec     float64
+	crossfadeSec   float64
+	normalize      playerpck.Normalization
+	outro          bool
+	controls       bool
+	sessionTime    time.Duration
+	sessionSongs   int
+	// names are the positional arguments: the name of the playlist, followed
+	// by song paths or db files
+	names []string
+}
+
+var playlistCommands = map[string]func(args playlistArguments, playlists *songrep.PlaylistRepository){
+	"create": runPlaylistCreate,
+	"add":    runPlaylistAdd,
+	"remove": runPlaylistRemove,
+	"list":   runPlaylistList,
+	"play":   runPlaylistPlay,
+}
+
+func runPlaylist(arguments []string) {
+	if len(arguments) == 0 {
+		playlistUsage()
+	}
+	command, found := playlistCommands[arguments[0]]
+	if !found {
+		playlistUsage()
+	}
+
+	var args playlistArguments
+	fs := flag.NewFlagSet("playlist "+arguments[0], flag.ExitOnError)
+	fs.StringVar(&args.ratings, "rating-file", "", "json file where ratings are store, the playlists are stored next to it")
+	if arguments[0] == "play" {
+		fs.BoolVar(&args.shuffle, "shuffle", false, "play the songs in a random order")
+		fs.BoolVar(&args.continuousPlay, "continuous", false, "don't stop to ask rating")
+		fs.BoolVar(&args.controls, "controls", false, "control the songs with commands followed by Enter (p: pause, f/b [SEC]: seek, s: skip, q: quit) instead of the keys of mplayer")
+		fs.IntVar(&args.maxPlays, "max-plays", 0, "maximum number of plays (default is 0, infinity)")
+		fs.IntVar(&args.maxPlayTime, "max-play-time", 0, "maximum time to play (default is 0, infinity)")
+		fs.Float64Var(&args.fadeInSec, "fade-in", 0, "duration in seconds of the fade-in at the start of the songs")
+		fs.DurationVar(&args.sessionTime, "session-time", 0, "stop after this listening time, like 45m, the last song fading out (default is 0, no limit)")
+		fs.IntVar(&args.sessionSongs, "session-songs", 0, "stop after this number of songs (default is 0, no limit)")
+		fs.BoolVar(&args.outro, "outro", false, "play the end of the songs after their loop end, at the end of the last loop (with -max-plays or -max-play-time)")
+		fs.TextVar(&args.normalize, "normalize", playerpck.NormalizeOff, "normalize the loudness of the songs, with the gains measured by scan -loudness: track, game or off")
+		fs.Float64Var(&args.crossfadeSec, "crossfade", 0, "duration in seconds of the crossfade between songs (with -continuous, and -max-plays or -max-play-time)")
+		fs.Float64Var(&args.fadeOutSec, "fade-out", 0, "duration in seconds of the fade-out at the end of the last loop (with -max-plays or -max-play-time)")
+	}
+	_ = fs.Parse(arguments[1:])
+	args.names = fs.Args()
+
+	// without it, the playlists would be stored in the current directory
+	if args.ratings == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide the rating file, next to which the playlists are stored")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if arguments[0] != "list" && len(args.names) == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide the name of the playlist")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	playlists := loadPlaylists(filepath.Join(filepath.Dir(args.ratings), playlistFileName))
+	command(args, &playlists)
+}
+
+func playlistUsage() {
+	_, _ = fmt.Fprintln(os.Stderr, "usage: vgsgo playlist create|add|remove|list|play [-rating-file FILE] ...")
+	os.Exit(1)
+}
+
+func loadPlaylists(file string) songrep.PlaylistRepository {
+	if _, err := os.Stat(file); err != nil {
+		return songrep.PlaylistRepository{File: file}
+	}
+	fh, err := os.Open(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rep := songrep.PlaylistsFromJSON(fh)
+	rep.File = file
+	_ = fh.Close()
+	return rep
+}
+
+func getPlaylist(name string, playlists *songrep.PlaylistRepository) *songrep.Playlist {
+	playlist, found := playlists.Get(name)
+	if !found {
+		fmt.Printf("playlist %q not found\n", name)
+		os.Exit(1)
+	}
+	return playlist
+}
+
+func runPlaylistCreate(args playlistArguments, playlists *songrep.PlaylistRepository) {
+	if _, err := playlists.Create(args.names[0]); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	playlists.Save()
+}
+
+// runPlaylistAdd adds song paths, as found in the metadata files, to the end
+// of the playlist.
+func runPlaylistAdd(args playlistArguments, playlists *songrep.PlaylistRepository) {
+	playlist := getPlaylist(args.names[0], playlists)
+	playlist.Add(args.names[1:]...)
+	playlists.Save()
+}
+
+func runPlaylistRemove(args playlistArguments, playlists *songrep.PlaylistRepository) {
+	playlist := getPlaylist(args.names[0], playlists)
+	for _, path := range args.names[1:] {
+		if playlist.Remove(path) == 0 {
+			fmt.Printf("%s is not in the playlist\n", path)
+		}
+	}
+	playlists.Save()
+}
+
+// runPlaylistList lists the playlists, or the songs of a playlist when its
+// name is given.
+func runPlaylistList(args playlistArguments, playlists *songrep.PlaylistRepository) {
+	if len(args.names) == 0 {
+		for _, playlist := range playlists.Playlists {
+			fmt.Printf("%s (%d songs)\n", playlist.Name, len(playlist.Paths))
+		}
+		return
+	}
+	playlist := getPlaylist(args.names[0], playlists)
+	for i, path := range playlist.Paths {
+		fmt.Printf("%3d. %s\n", i+1, path)
+	}
+}
+
+// runPlaylistPlay plays the playlist, with the songs found in the db files
+// given after its name.
+func runPlaylistPlay(args playlistArguments, playlists *songrep.PlaylistRepository) {
+	playlist := getPlaylist(args.names[0], playlists)
+	if len(args.names) < 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide one or more db files")
+		os.Exit(1)
+	}
+	if args.maxPlays != 0 && args.maxPlayTime != 0 {
+		fmt.Println("You can't use -max-plays and -max-play-time at the same time")
+		os.Exit(1)
+	}
+
+	songs, missing := songrep.ResolvePlaylist(*playlist, songrep.SongsFromFiles(args.names[1:]))
+	for _, path := range missing {
+		_, _ = fmt.Fprintf(os.Stderr, "%s not found, skipped\n", path)
+	}
+
+	ratingRep := loadRatings(args.ratings)
+	songRep := songrep.PlaylistSongRepository{
+		InMemorySongRepository: songrep.InMemorySongRepository{Songs: songs, RatingRepository: ratingRep},
+		Shuffle:                args.shuffle,
+	}
+	player := makePlayer(args.maxPlays, args.maxPlayTime, args.continuousPlay)
+	player.FadeInSec = float32(args.fadeInSec)
+	player.FadeOutSec = float32(args.fadeOutSec)
+	player.CrossfadeSec = float32(args.crossfadeSec)
+	player.Normalize = args.normalize
+	player.Outro = args.outro
+	if args.controls {
+		player.Controls = playerpck.ReadControls(os.Stdin)
+		player.Input = player.Controls
+	}
+	checkCrossfade(player, args.controls)
+	session := playerpck.Session{MaxSongs: args.sessionSongs, MaxTime: args.sessionTime, Start: time.Now()}
+	run(&songRep, &ratingRep, player, songrep.Filters{}, &session)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"vgsgo/songrep"
+)
+
+// exportSongs writes the songs matching the filters to the playlist file of
+// -export, in the format of its extension.
+func exportSongs(args Arguments, filters songrep.Filters, selection songrep.Selection) {
+	ratingRep := loadRatings(args.ratings)
+	ratingRep.Aggregator = getRatingAggregator(args, &ratingRep)
+	songRep := songrep.InMemorySongRepository{
+		Songs:            songrep.SongsFromFiles(args.dbFiles),
+		RatingRepository: ratingRep,
+		Selection:        selection,
+	}
+	songs := songRep.FilteredSongs(filters)
+
+	isPLS := isPLSFile(args.exportFile)
+	fh, err := os.OpenFile(args.exportFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if isPLS {
+		songrep.WritePLS(fh, songs)
+	} else {
+		songrep.WriteM3U8(fh, songs)
+	}
+	_ = fh.Close()
+	fmt.Printf("%d songs exported to %s\n", len(songs), args.exportFile)
+}
+
+func readPlaylistFile(file string) []songrep.PlaylistEntry {
+	fh, err := os.Open(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	abs, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if isPLSFile(file) {
+		return songrep.ReadPLS(fh, abs)
+	}
+	return songrep.ReadM3U(fh, abs)
+}
+
+func isPLSFile(file string) bool {
+	return strings.EqualFold(filepath.Ext(file), ".pls")
+}
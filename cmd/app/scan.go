@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"vgsgo/audio"
+	"vgsgo/formats"
+	"vgsgo/songrep"
+)
+
+// runScan reads the headers of the music files, and compares them with the
+// metadata files.
+func runScan(arguments []string) {
+	fs := flag.NewFlagSet("scan", flag.ExitOnError)
+	all := fs.Bool("all", false, "also print the songs whose metadata match their header")
+	loudness := fs.Bool("loudness", false, "measure the loudness of the songs without gain (or read their ReplayGain tags) with ffmpeg, and store their gain in the metadata files")
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	songs := songrep.SongsFromFiles(fs.Args())
+	var checked, mismatched, unknown, failed int
+	for _, song := range songs {
+		info, err := formats.ReadFile(song.AbsPath)
+		if errors.Is(err, formats.ErrUnknownFormat) {
+			unknown++
+			continue
+		}
+		if err != nil {
+			fmt.Printf("%s: %v\n", song.Path, err)
+			failed++
+			continue
+		}
+
+		checked++
+		mismatches := info.Mismatches(song.DurationSec, song.LoopStartMicro, song.LoopEndMicro)
+		if len(mismatches) > 0 {
+			mismatched++
+		}
+		if len(mismatches) > 0 || *all {
+			loop := "no loop"
+			if info.Loop {
+				loop = fmt.Sprintf("loop %d-%d", info.LoopStart, info.LoopEnd)
+			}
+			fmt.Printf("%s: %s, %d Hz, %d channels, %d samples, %s\n", song.Path, info.Format, info.SampleRate, info.Channels, info.Samples, loop)
+		}
+		for _, mismatch := range mismatches {
+			fmt.Printf("  %s\n", mismatch)
+		}
+	}
+	fmt.Printf("%d files checked, %d with different metadata, %d unreadable, %d of unknown format\n", checked, mismatched, failed, unknown)
+
+	if *loudness {
+		scanGains(songs)
+	}
+}
+
+// scanGains measures the gains of the songs that don't have one yet, and
+// writes them to their metadata files.
+func scanGains(songs []songrep.Song) {
+	updates := make(map[string]map[string]map[string]interface{})
+	var measured, failed int
+	for _, song := range songs {
+		if song.HasGain {
+			continue
+		}
+		gain, err := audio.Gain(song.AbsPath)
+		if err != nil {
+			fmt.Printf("%s: %v\n", song.Path, err)
+			failed++
+			continue
+		}
+		gain = math.Round(gain*100) / 100
+		fmt.Printf("%s: gain %.2f dB\n", song.Path, gain)
+		if updates[song.MetadataFile] == nil {
+			updates[song.MetadataFile] = make(map[string]map[string]interface{})
+		}
+		updates[song.MetadataFile][song.Path] = map[string]interface{}{"gain": gain}
+		measured++
+	}
+
+	for file, fileUpdates := range updates {
+		songrep.UpdateSongMetadata(file, fileUpdates)
+	}
+	fmt.Printf("%d gains measured, %d failed\n", measured, failed)
+}
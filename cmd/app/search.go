@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	playerpck "vgsgo/player"
+	"vgsgo/songrep"
+)
+
+func runSearch(arguments []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+	ratings := fs.String("rating-file", "", "json file where ratings are store")
+	limit := fs.Int("limit", 20, "maximum number of results (0 for all)")
+	maxPlays := fs.Int("max-plays", 0, "maximum number of plays (default is 0, infinity)")
+	maxPlayTime := fs.Int("max-play-time", 0, "maximum time to play (default is 0, infinity)")
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() < 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide the search terms and one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *maxPlays != 0 && *maxPlayTime != 0 {
+		fmt.Println("You can't use -max-plays and -max-play-time at the same time")
+		os.Exit(1)
+	}
+
+	ratingRep := loadRatings(*ratings)
+	songs := songrep.SongsFromFiles(fs.Args()[1:])
+	results := songrep.Search(songs, fs.Arg(0))
+	if len(results) == 0 {
+		fmt.Println("no song found")
+		return
+	}
+	if *limit > 0 && len(results) > *limit {
+		results = results[:*limit]
+	}
+
+	for i, result := range results {
+		rating := "-"
+		if r, found := ratingRep.Rating(result.Song); found {
+			rating = fmt.Sprintf("%.2f", r)
+		}
+		gameTitle := ""
+		if result.Song.Game != nil {
+			gameTitle = result.Song.Game.Title
+		}
+		fmt.Printf("%3d. %s - %s (rating: %s, plays: %d)\n", i+1, gameTitle, result.Song.Title, rating, len(ratingRep.Plays(result.Song)))
+	}
+
+	song, found := chooseResult(results)
+	if !found {
+		return
+	}
+
+	player := makePlayer(*maxPlays, *maxPlayTime, false)
+	report := player.Play(song)
+	switch report.End {
+	case playerpck.EndError:
+		_, _ = fmt.Fprintf(os.Stderr, "%s: the player failed, skipped\n", song.Path)
+		return
+	case playerpck.EndQuit:
+		return
+	}
+	play := songrep.Play{
+		ListenedSec: report.ListenedSec,
+		Loops:       report.Loops,
+		Skipped:     report.Skipped,
+	}
+	// a song skipped with the controls is not rated
+	var actions playerpck.RatingAction
+	if report.End == playerpck.EndFinished {
+		actions = player.Rate()
+		play.Rating = actions.Value
+	}
+	play.Timestamp = int(time.Now().Unix())
+	ratingRep.AddPlay(song, play)
+	ratingRep.Save()
+	if actions.Resume {
+		player.PlayIndefinitely(song)
+	}
+}
+
+// chooseResult asks for the number of the result to play. An empty answer
+// plays nothing.
+func chooseResult(results []songrep.SearchResult) (songrep.Song, bool) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Printf("Play (1-%d, empty to quit)? ", len(results))
+		if !scanner.Scan() {
+			return songrep.Song{}, false
+		}
+		answer := strings.TrimSpace(scanner.Text())
+		if answer == "" {
+			return songrep.Song{}, false
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(results) {
+			return results[n-1].Song, true
+		}
+	}
+}
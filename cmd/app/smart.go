@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"vgsgo/songrep"
+)
+
+// smartPlaylistFileName is the file where the smart playlists are stored, in
+// the directory of the rating file.
+const smartPlaylistFileName = "smart_playlists.json"
+
+func loadSmartPlaylists(ratingFile string) songrep.SmartPlaylistRepository {
+	file := filepath.Join(filepath.Dir(ratingFile), smartPlaylistFileName)
+	if _, err := os.Stat(file); err != nil {
+		return songrep.SmartPlaylistRepository{File: file}
+	}
+	fh, err := os.Open(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rep := songrep.SmartPlaylistsFromJSON(fh)
+	rep.File = file
+	_ = fh.Close()
+	return rep
+}
+
+func saveSmartPlaylist(ratingFile string, playlist songrep.SmartPlaylist) {
+	if err := playlist.Filters.Validate(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	playlists := loadSmartPlaylists(ratingFile)
+	playlists.Set(playlist)
+	playlists.Save()
+	fmt.Printf("smart playlist %q saved in %s\n", playlist.Name, playlists.File)
+}
+
+func getSmartPlaylist(ratingFile, name string) songrep.SmartPlaylist {
+	playlists := loadSmartPlaylists(ratingFile)
+	playlist, found := playlists.Get(name)
+	if !found {
+		fmt.Printf("smart playlist %q not found in %s\n", name, playlists.File)
+		os.Exit(1)
+	}
+	return playlist
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"vgsgo/songrep"
+	"vgsgo/stats"
+)
+
+func runStats(arguments []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	ratings := fs.String("rating-file", "", "json file where ratings are store")
+	asJSON := fs.Bool("json", false, "output json instead of text tables")
+	top := fs.Int("top", 10, "number of songs and games in the most played and highest rated lists (0 for all)")
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() == 0 || *ratings == "" {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide a rating file and one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ratingRep := loadRatings(*ratings)
+	songs := songrep.SongsFromFiles(fs.Args())
+	report := stats.Compute(songs, &ratingRep, stats.Options{Top: *top, Location: time.Local})
+
+	if *asJSON {
+		stats.WriteJSON(os.Stdout, report)
+	} else {
+		stats.WriteText(os.Stdout, report)
+	}
+}
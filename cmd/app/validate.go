@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"vgsgo/formats"
+	"vgsgo/songrep"
+)
+
+// validationReport is the output of the validate command.
+type validationReport struct {
+	Songs    int               `json:"songs"`
+	Problems []songrep.Problem `json:"problems"`
+}
+
+// runValidate checks the metadata files, and prints the problems as json. It
+// exits with the status 1 when there are problems.
+func runValidate(arguments []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	_ = fs.Parse(arguments)
+
+	if fs.NArg() == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "You must provide one or more db files")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	songs := songrep.SongsFromFiles(fs.Args())
+	report := validationReport{Songs: len(songs), Problems: songrep.Validate(songs)}
+	report.Problems = append(report.Problems, headerProblems(songs)...)
+
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println(string(content))
+	if len(report.Problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+// headerProblems compares the metadata of the songs with the headers of
+// their files, like scan. Missing files and files of unknown formats are
+// skipped.
+func headerProblems(songs []songrep.Song) []songrep.Problem {
+	problems := make([]songrep.Problem, 0)
+	for _, song := range songs {
+		if _, err := os.Stat(song.AbsPath); err != nil {
+			continue
+		}
+		info, err := formats.ReadFile(song.AbsPath)
+		if errors.Is(err, formats.ErrUnknownFormat) {
+			continue
+		}
+		var messages []string
+		if err != nil {
+			messages = []string{err.Error()}
+		} else {
+			messages = info.Mismatches(song.DurationSec, song.LoopStartMicro, song.LoopEndMicro)
+		}
+		for _, message := range messages {
+			problems = append(problems, songrep.Problem{Path: song.Path, MetadataFile: song.MetadataFile, Kind: songrep.ProblemHeader, Message: message})
+		}
+	}
+	return problems
+}
+
+// warnProblems warns about the problems of the metadata files that can be
+// found when they are loaded, see the validate command.
+func warnProblems(songs []songrep.Song) {
+	for _, problem := range songrep.Validate(songs) {
+		_, _ = fmt.Fprintf(os.Stderr, "warning: %s\n", problem)
+	}
+}
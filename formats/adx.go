@@ -0,0 +1,41 @@
+package formats
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ADX is the format of CRI Middleware. The loop points are in the header
+// after the version, at offsets that depend on the version, and only if the
+// header is long enough.
+func init() {
+	Register(Parser{Name: "ADX", Extensions: []string{".adx"}, Signature: []byte{0x80, 0x00}, Parse: parseADX})
+}
+
+func parseADX(ra io.ReaderAt, size int64) (Info, error) {
+	r := &reader{r: ra, order: binary.BigEndian}
+	dataOffset := int64(r.u16(0x02)) + 4
+	if r.err == nil && string(r.bytes(dataOffset-6, 6)) != "(c)CRI" {
+		return Info{}, errors.New("copyright not found")
+	}
+	info := Info{
+		Channels:   int(r.u8(0x07)),
+		SampleRate: int(r.u32(0x08)),
+		Samples:    int64(r.u32(0x0c)),
+	}
+
+	var loopOffset int64
+	switch version := r.u8(0x12); {
+	case version == 3 && dataOffset >= 0x2c:
+		loopOffset = 0x18
+	case version == 4 && dataOffset >= 0x38:
+		loopOffset = 0x24
+	}
+	if loopOffset != 0 && r.u32(loopOffset) != 0 {
+		info.Loop = true
+		info.LoopStart = int64(r.u32(loopOffset + 0x04))
+		info.LoopEnd = int64(r.u32(loopOffset + 0x0c))
+	}
+	return info, r.err
+}
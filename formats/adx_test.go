@@ -0,0 +1,53 @@
+package formats
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeADX(version uint8, dataOffset int, loop bool) []byte {
+	f := &fixture{order: binary.BigEndian}
+	f.u16(0x00, 0x8000).u16(0x02, uint16(dataOffset-4))
+	f.u8(0x04, 3).u8(0x05, 18).u8(0x06, 4).u8(0x07, 2).u32(0x08, 44100).u32(0x0c, 441000).u8(0x12, version)
+	loopOffset := 0x18
+	if version == 4 {
+		loopOffset = 0x24
+	}
+	if loop {
+		f.u32(loopOffset, 1).u32(loopOffset+0x04, 44100).u32(loopOffset+0x0c, 400000)
+	}
+	f.bytes(dataOffset-6, []byte("(c)CRI"))
+	return f.buf
+}
+
+func Test_parseADX(t *testing.T) {
+	noLoop := Info{Format: "ADX", SampleRate: 44100, Channels: 2, Samples: 441000}
+	loop := Info{Format: "ADX", SampleRate: 44100, Channels: 2, Samples: 441000, Loop: true, LoopStart: 44100, LoopEnd: 400000}
+	tests := []struct {
+		name       string
+		version    uint8
+		dataOffset int
+		loop       bool
+		want       Info
+	}{
+		{"version 3", 3, 0x800, true, loop},
+		{"version 4", 4, 0x800, true, loop},
+		{"version 3, no loop", 3, 0x800, false, noLoop},
+		{"version 4, short header", 4, 0x34, true, noLoop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := read(t, makeADX(tt.version, tt.dataOffset, tt.loop), "a.adx")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, info)
+		})
+	}
+}
+
+func Test_parseADX_errors(t *testing.T) {
+	noCopyright := makeADX(3, 0x800, true)
+	copy(noCopyright[0x800-6:], "xxxxxx")
+	_, err := read(t, noCopyright, "a.adx")
+	assert.ErrorContains(t, err, "copyright not found")
+}
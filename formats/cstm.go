@@ -0,0 +1,57 @@
+package formats
+
+import (
+	"errors"
+	"io"
+)
+
+// BCSTM (3DS) and BFSTM (Wii U, Switch) share the same layout: a table of
+// section references, the INFO section starting with a reference to the
+// stream info.
+func init() {
+	Register(Parser{Name: "BCSTM", Extensions: []string{".bcstm"}, Signature: []byte("CSTM"), Parse: parseCSTM})
+	Register(Parser{Name: "BFSTM", Extensions: []string{".bfstm"}, Signature: []byte("FSTM"), Parse: parseCSTM})
+}
+
+const (
+	cstmInfoSection    = 0x4000
+	cstmStreamInfoType = 0x4100
+)
+
+func parseCSTM(ra io.ReaderAt, size int64) (Info, error) {
+	r := &reader{r: ra}
+	order, err := byteOrder(r.bytes(0x04, 2))
+	if err != nil {
+		return Info{}, err
+	}
+	r.order = order
+
+	var infoSection int64
+	sections := int(r.u16(0x10))
+	for i := 0; i < sections && r.err == nil; i++ {
+		ref := int64(0x14 + 0x0c*i)
+		if r.u16(ref) == cstmInfoSection {
+			infoSection = int64(r.u32(ref + 0x04))
+		}
+	}
+	if r.err != nil {
+		return Info{}, r.err
+	}
+	if infoSection == 0 || string(r.bytes(infoSection, 4)) != "INFO" {
+		return Info{}, errors.New("INFO section not found")
+	}
+	if r.u16(infoSection+0x08) != cstmStreamInfoType {
+		return Info{}, errors.New("stream info not found")
+	}
+
+	stream := infoSection + 0x08 + int64(r.u32(infoSection+0x0c))
+	info := Info{
+		Loop:       r.u8(stream+0x01) != 0,
+		Channels:   int(r.u8(stream + 0x02)),
+		SampleRate: int(r.u32(stream + 0x04)),
+		LoopStart:  int64(r.u32(stream + 0x08)),
+		Samples:    int64(r.u32(stream + 0x0c)),
+	}
+	info.LoopEnd = info.Samples
+	return info, r.err
+}
@@ -0,0 +1,52 @@
+package formats
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeCSTM(magic string, order binary.ByteOrder) []byte {
+	f := &fixture{order: order}
+	f.bytes(0x00, []byte(magic)).u16(0x04, 0xfeff).u16(0x10, 3)
+	// section references: INFO is the first one, like in the real files
+	f.u16(0x14, 0x4000).u32(0x18, 0x40)
+	f.u16(0x20, 0x4001).u32(0x24, 0x200)
+	f.u16(0x2c, 0x4002).u32(0x30, 0x300)
+	// INFO section, the stream info is at 0x40+0x08+0x18
+	f.bytes(0x40, []byte("INFO")).u16(0x48, 0x4100).u32(0x4c, 0x18)
+	stream := 0x60
+	f.u8(stream+0x00, 2).u8(stream+0x01, 1).u8(stream+0x02, 1).u32(stream+0x04, 48000).u32(stream+0x08, 96000).u32(stream+0x0c, 480000)
+	return f.buf
+}
+
+func Test_parseCSTM(t *testing.T) {
+	tests := []struct {
+		name  string
+		magic string
+		order binary.ByteOrder
+	}{
+		{"BCSTM", "CSTM", binary.LittleEndian},
+		{"BFSTM", "FSTM", binary.BigEndian},
+		{"BFSTM", "FSTM", binary.LittleEndian},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := read(t, makeCSTM(tt.magic, tt.order), "a")
+			assert.NoError(t, err)
+			assert.Equal(t, Info{Format: tt.name, SampleRate: 48000, Channels: 1, Samples: 480000, Loop: true, LoopStart: 96000, LoopEnd: 480000}, info)
+		})
+	}
+}
+
+func Test_parseCSTM_errors(t *testing.T) {
+	noInfo := makeCSTM("CSTM", binary.LittleEndian)
+	binary.LittleEndian.PutUint16(noInfo[0x14:], 0x4003)
+	_, err := read(t, noInfo, "a.bcstm")
+	assert.ErrorContains(t, err, "INFO section not found")
+
+	badBOM := makeCSTM("CSTM", binary.LittleEndian)
+	badBOM[0x04] = 0
+	_, err = read(t, badBOM, "a.bcstm")
+	assert.ErrorContains(t, err, "invalid byte order mark")
+}
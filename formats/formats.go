@@ -0,0 +1,205 @@
+// Package formats reads the headers of the audio containers of video game
+// rips: sample rate, length and loop points.
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Info is what the header of a file tells about its stream. Positions are
+// in samples, LoopEnd is exclusive.
+type Info struct {
+	Format     string
+	SampleRate int
+	Channels   int
+	Samples    int64
+	Loop       bool
+	LoopStart  int64
+	LoopEnd    int64
+}
+
+func (i Info) DurationSec() float64 {
+	return float64(i.Samples) / float64(i.SampleRate)
+}
+
+func (i Info) LoopStartMicro() int {
+	return samplesToMicro(i.LoopStart, i.SampleRate)
+}
+
+func (i Info) LoopEndMicro() int {
+	return samplesToMicro(i.LoopEnd, i.SampleRate)
+}
+
+func samplesToMicro(samples int64, sampleRate int) int {
+	return int((samples*1000000 + int64(sampleRate)/2) / int64(sampleRate))
+}
+
+const (
+	durationToleranceSec = 0.1
+	loopToleranceMicro   = 10000
+)
+
+// Mismatches compares the metadata of a song with its header, and describes
+// the differences. A loop end of 0 is the end of the song.
+func (i Info) Mismatches(durationSec float32, loopStartMicro, loopEndMicro int) []string {
+	mismatches := make([]string, 0)
+	if math.Abs(float64(durationSec)-i.DurationSec()) > durationToleranceSec {
+		mismatches = append(mismatches, fmt.Sprintf("duration is %.2fs, the file says %.2fs", durationSec, i.DurationSec()))
+	}
+
+	hasLoopPoints := loopStartMicro != 0 || loopEndMicro != 0
+	switch {
+	case !i.Loop && hasLoopPoints:
+		mismatches = append(mismatches, "the file has no loop")
+	case i.Loop && !hasLoopPoints:
+		mismatches = append(mismatches, fmt.Sprintf("no loop points, the file loops from %dµs to %dµs", i.LoopStartMicro(), i.LoopEndMicro()))
+	case i.Loop:
+		if loopEndMicro == 0 {
+			loopEndMicro = samplesToMicro(i.Samples, i.SampleRate)
+		}
+		if abs(loopStartMicro-i.LoopStartMicro()) > loopToleranceMicro {
+			mismatches = append(mismatches, fmt.Sprintf("loop start is %dµs, the file says %dµs", loopStartMicro, i.LoopStartMicro()))
+		}
+		if abs(loopEndMicro-i.LoopEndMicro()) > loopToleranceMicro {
+			mismatches = append(mismatches, fmt.Sprintf("loop end is %dµs, the file says %dµs", loopEndMicro, i.LoopEndMicro()))
+		}
+	}
+	return mismatches
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Parser reads the header of a format. Files are recognized by the
+// Signature at their start, or by one of the Extensions when no signature
+// matches.
+type Parser struct {
+	Name       string
+	Extensions []string
+	Signature  []byte
+	Parse      func(r io.ReaderAt, size int64) (Info, error)
+}
+
+var parsers = make([]Parser, 0)
+
+// Register adds a parser to the registry.
+func Register(parser Parser) {
+	parsers = append(parsers, parser)
+}
+
+// ErrUnknownFormat is returned for files that no parser recognizes.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// Detect finds the parser of a file, from the start of its content and its
+// name.
+func Detect(start []byte, name string) (Parser, bool) {
+	for _, parser := range parsers {
+		if bytes.HasPrefix(start, parser.Signature) {
+			return parser, true
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, parser := range parsers {
+		for _, e := range parser.Extensions {
+			if e == ext {
+				return parser, true
+			}
+		}
+	}
+	return Parser{}, false
+}
+
+func ReadFile(path string) (Info, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+	stat, err := fh.Stat()
+	if err != nil {
+		return Info{}, err
+	}
+	return Read(fh, stat.Size(), path)
+}
+
+// Read reads the header of the content of a file, named name.
+func Read(r io.ReaderAt, size int64, name string) (Info, error) {
+	start := make([]byte, 16)
+	n, err := r.ReadAt(start, 0)
+	if err != nil && err != io.EOF {
+		return Info{}, err
+	}
+	parser, found := Detect(start[:n], name)
+	if !found {
+		return Info{}, ErrUnknownFormat
+	}
+	info, err := parser.Parse(r, size)
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", parser.Name, err)
+	}
+	if info.SampleRate <= 0 {
+		return Info{}, fmt.Errorf("%s: invalid sample rate %d", parser.Name, info.SampleRate)
+	}
+	info.Format = parser.Name
+	return info, nil
+}
+
+// reader reads numbers at offsets of a file. The first error is kept, and
+// the following reads return 0.
+type reader struct {
+	r     io.ReaderAt
+	order binary.ByteOrder
+	err   error
+}
+
+func (r *reader) bytes(offset int64, n int) []byte {
+	buf := make([]byte, n)
+	if r.err != nil {
+		return buf
+	}
+	if _, err := r.r.ReadAt(buf, offset); err != nil {
+		if err == io.EOF {
+			err = errors.New("truncated header")
+		}
+		r.err = err
+	}
+	return buf
+}
+
+func (r *reader) u8(offset int64) uint8 {
+	return r.bytes(offset, 1)[0]
+}
+
+func (r *reader) u16(offset int64) uint16 {
+	return r.order.Uint16(r.bytes(offset, 2))
+}
+
+func (r *reader) u32(offset int64) uint32 {
+	return r.order.Uint32(r.bytes(offset, 4))
+}
+
+// byteOrder reads a byte order mark, 0xfeff in the order of the file.
+func byteOrder(bom []byte) (binary.ByteOrder, error) {
+	switch {
+	case bom[0] == 0xfe && bom[1] == 0xff:
+		return binary.BigEndian, nil
+	case bom[0] == 0xff && bom[1] == 0xfe:
+		return binary.LittleEndian, nil
+	default:
+		return nil, errors.New("invalid byte order mark")
+	}
+}
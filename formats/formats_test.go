@@ -0,0 +1,117 @@
+package formats
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixture builds a file header, growing as values are put in it.
+type fixture struct {
+	buf   []byte
+	order binary.ByteOrder
+}
+
+func (f *fixture) grow(end int) {
+	if len(f.buf) < end {
+		f.buf = append(f.buf, make([]byte, end-len(f.buf))...)
+	}
+}
+
+func (f *fixture) bytes(offset int, b []byte) *fixture {
+	f.grow(offset + len(b))
+	copy(f.buf[offset:], b)
+	return f
+}
+
+func (f *fixture) u8(offset int, v uint8) *fixture {
+	return f.bytes(offset, []byte{v})
+}
+
+func (f *fixture) u16(offset int, v uint16) *fixture {
+	f.grow(offset + 2)
+	f.order.PutUint16(f.buf[offset:], v)
+	return f
+}
+
+func (f *fixture) u32(offset int, v uint32) *fixture {
+	f.grow(offset + 4)
+	f.order.PutUint32(f.buf[offset:], v)
+	return f
+}
+
+func read(t *testing.T, content []byte, name string) (Info, error) {
+	t.Helper()
+	return Read(bytes.NewReader(content), int64(len(content)), name)
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     []byte
+		file      string
+		wantName  string
+		wantFound bool
+	}{
+		{"signature", []byte("RSTM\xfe\xff"), "a.bin", "BRSTM", true},
+		{"signature over extension", []byte("CSTM"), "a.brstm", "BCSTM", true},
+		{"extension", []byte("????"), "a.BFSTM", "BFSTM", true},
+		{"hps", []byte(" HALPST\x00"), "a", "HPS", true},
+		{"unknown", []byte("ID3"), "a.mp3", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := Detect(tt.start, tt.file)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.wantName, got.Name)
+		})
+	}
+}
+
+func TestRead_errors(t *testing.T) {
+	_, err := read(t, []byte("ID3 some mp3"), "a.mp3")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = read(t, []byte("RSTM\xfe\xff"), "a.brstm")
+	assert.ErrorContains(t, err, "BRSTM: truncated header")
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.brstm")
+	assert.NoError(t, os.WriteFile(path, makeRSTM(binary.BigEndian, true), 0600))
+	info, err := ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "BRSTM", info.Format)
+
+	_, err = ReadFile(filepath.Join(t.TempDir(), "missing.brstm"))
+	assert.Error(t, err)
+}
+
+func TestInfo_Mismatches(t *testing.T) {
+	info := Info{SampleRate: 32000, Samples: 320000, Loop: true, LoopStart: 32000, LoopEnd: 320000}
+	noLoop := Info{SampleRate: 32000, Samples: 320000}
+	tests := []struct {
+		name           string
+		info           Info
+		durationSec    float32
+		loopStartMicro int
+		loopEndMicro   int
+		want           []string
+	}{
+		{"same", info, 10, 1000000, 10000000, []string{}},
+		{"loop end 0", info, 10.05, 1005000, 0, []string{}},
+		{"duration", info, 12, 1000000, 0, []string{"duration is 12.00s, the file says 10.00s"}},
+		{"loop points", info, 10, 2000000, 9000000, []string{"loop start is 2000000µs, the file says 1000000µs", "loop end is 9000000µs, the file says 10000000µs"}},
+		{"no loop points", info, 10, 0, 0, []string{"no loop points, the file loops from 1000000µs to 10000000µs"}},
+		{"no loop", noLoop, 10, 1000000, 0, []string{"the file has no loop"}},
+		{"no loop, no loop points", noLoop, 10, 0, 0, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.info.Mismatches(tt.durationSec, tt.loopStartMicro, tt.loopEndMicro))
+		})
+	}
+}
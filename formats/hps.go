@@ -0,0 +1,69 @@
+package formats
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// HPS is the stream format of HAL Laboratory (Super Smash Bros. Melee,
+// Kirby Air Ride). Its DSP ADPCM data is a chain of blocks: the song loops
+// when the last block points back to a previous one.
+func init() {
+	Register(Parser{Name: "HPS", Extensions: []string{".hps"}, Signature: []byte(" HALPST\x00"), Parse: parseHPS})
+}
+
+const hpsFirstBlock = 0x80
+
+func parseHPS(ra io.ReaderAt, size int64) (Info, error) {
+	r := &reader{r: ra, order: binary.BigEndian}
+	info := Info{
+		SampleRate: int(r.u32(0x08)),
+		Channels:   int(r.u32(0x0c)),
+		// end address, in nibbles, of the first channel
+		Samples: dspNibblesToSamples(int64(r.u32(0x18))) + 1,
+	}
+	if r.err != nil {
+		return Info{}, r.err
+	}
+	if info.Channels < 1 || info.Channels > 2 {
+		return Info{}, errors.New("invalid channel count")
+	}
+
+	// the position, in nibbles of each channel, of the blocks
+	positions := make(map[int64]int64)
+	nibbles := int64(0)
+	for offset := int64(hpsFirstBlock); ; {
+		if offset < hpsFirstBlock || offset >= size {
+			return Info{}, errors.New("invalid block offset")
+		}
+		positions[offset] = nibbles
+		nibbles += int64(r.u32(offset)) / int64(info.Channels) * 2
+		next := int64(int32(r.u32(offset + 0x08)))
+		if r.err != nil {
+			return Info{}, r.err
+		}
+		if next == -1 {
+			break
+		}
+		if position, found := positions[next]; found {
+			info.Loop = true
+			info.LoopStart = dspNibblesToSamples(position)
+			info.LoopEnd = info.Samples
+			break
+		}
+		offset = next
+	}
+	return info, nil
+}
+
+// dspNibblesToSamples converts a position in a DSP ADPCM channel: frames are
+// 8 bytes, a header byte followed by 14 samples of 4 bits.
+func dspNibblesToSamples(nibbles int64) int64 {
+	frames := nibbles / 16
+	remainder := nibbles % 16
+	if remainder > 0 {
+		return frames*14 + remainder - 2
+	}
+	return frames * 14
+}
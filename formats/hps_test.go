@@ -0,0 +1,61 @@
+package formats
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// makeHPS makes a stereo HPS with 3 blocks of 0x1000 bytes (0x800 per
+// channel, 256 frames of 14 samples). The last block points back to loopTo,
+// or ends the song if it is -1.
+func makeHPS(loopTo int32) []byte {
+	f := &fixture{order: binary.BigEndian}
+	f.bytes(0x00, []byte(" HALPST\x00")).u32(0x08, 32000).u32(0x0c, 2)
+	// end address in nibbles: 3 blocks of 0x800 bytes, minus 1 (the last
+	// nibble) plus 2 (the frame header)
+	end := uint32(3*0x800*2 - 1)
+	f.u32(0x18, end).u32(0x50, end)
+	blocks := []int{0x80, 0x10a0, 0x20c0}
+	for i, block := range blocks {
+		f.u32(block, 0x1000)
+		if i < len(blocks)-1 {
+			f.u32(block+0x08, uint32(blocks[i+1]))
+		} else {
+			f.u32(block+0x08, uint32(loopTo))
+		}
+		f.grow(block + 0x20 + 0x1000)
+	}
+	return f.buf
+}
+
+func Test_parseHPS(t *testing.T) {
+	samples := int64(3 * 256 * 14)
+	tests := []struct {
+		name   string
+		loopTo int32
+		want   Info
+	}{
+		{"no loop", -1, Info{Format: "HPS", SampleRate: 32000, Channels: 2, Samples: samples}},
+		{"loop to the first block", 0x80, Info{Format: "HPS", SampleRate: 32000, Channels: 2, Samples: samples, Loop: true, LoopStart: 0, LoopEnd: samples}},
+		{"loop to the second block", 0x10a0, Info{Format: "HPS", SampleRate: 32000, Channels: 2, Samples: samples, Loop: true, LoopStart: 256 * 14, LoopEnd: samples}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := read(t, makeHPS(tt.loopTo), "a.hps")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, info)
+		})
+	}
+}
+
+func Test_parseHPS_errors(t *testing.T) {
+	_, err := read(t, makeHPS(0x7fffff), "a.hps")
+	assert.ErrorContains(t, err, "invalid block offset")
+}
+
+func Test_dspNibblesToSamples(t *testing.T) {
+	assert.Equal(t, int64(0), dspNibblesToSamples(0))
+	assert.Equal(t, int64(14), dspNibblesToSamples(16))
+	assert.Equal(t, int64(15), dspNibblesToSamples(19))
+}
@@ -0,0 +1,61 @@
+package formats
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// NUS3BANK is the sound bank of Namco (Super Smash Bros. for Wii U). Its
+// table of contents lists the sizes of the sections, the PACK section holds
+// the stream, in the IDSP format.
+func init() {
+	Register(Parser{Name: "NUS3", Extensions: []string{".nus3bank"}, Signature: []byte("NUS3"), Parse: parseNUS3})
+	Register(Parser{Name: "IDSP", Extensions: []string{".idsp"}, Signature: []byte("IDSP"), Parse: parseIDSP})
+}
+
+func parseNUS3(ra io.ReaderAt, size int64) (Info, error) {
+	r := &reader{r: ra, order: binary.LittleEndian}
+	if string(r.bytes(0x08, 8)) != "BANKTOC " {
+		return Info{}, errors.New("table of contents not found")
+	}
+	tocSize := int64(r.u32(0x10))
+	entries := int(r.u32(0x14))
+
+	section := 0x14 + tocSize
+	for i := 0; i < entries && r.err == nil; i++ {
+		entry := int64(0x18 + 0x08*i)
+		sectionSize := int64(r.u32(entry + 0x04))
+		if string(r.bytes(entry, 4)) == "PACK" {
+			stream := section + 0x08
+			if r.err == nil && string(r.bytes(stream, 4)) != "IDSP" {
+				return Info{}, errors.New("unsupported stream in PACK section")
+			}
+			if r.err != nil {
+				return Info{}, r.err
+			}
+			return parseIDSP(io.NewSectionReader(ra, stream, size-stream), size-stream)
+		}
+		section += 0x08 + sectionSize
+	}
+	if r.err != nil {
+		return Info{}, r.err
+	}
+	return Info{}, errors.New("PACK section not found")
+}
+
+func parseIDSP(ra io.ReaderAt, size int64) (Info, error) {
+	r := &reader{r: ra, order: binary.BigEndian}
+	info := Info{
+		Channels:   int(r.u32(0x08)),
+		SampleRate: int(r.u32(0x0c)),
+		Samples:    int64(r.u32(0x10)),
+		LoopStart:  int64(r.u32(0x14)),
+		LoopEnd:    int64(r.u32(0x18)),
+	}
+	info.Loop = info.LoopEnd > 0
+	if !info.Loop {
+		info.LoopStart = 0
+	}
+	return info, r.err
+}
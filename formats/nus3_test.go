@@ -0,0 +1,51 @@
+package formats
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeIDSP(loopStart, loopEnd uint32) []byte {
+	f := &fixture{order: binary.BigEndian}
+	f.bytes(0x00, []byte("IDSP")).u32(0x08, 2).u32(0x0c, 48000).u32(0x10, 960000).u32(0x14, loopStart).u32(0x18, loopEnd)
+	f.u32(0x1c, 0x10).u32(0x20, 0x40).grow(0x100)
+	return f.buf
+}
+
+func makeNUS3(stream []byte) []byte {
+	f := &fixture{order: binary.LittleEndian}
+	f.bytes(0x00, []byte("NUS3")).bytes(0x08, []byte("BANKTOC "))
+	sections := []struct {
+		magic string
+		data  []byte
+	}{{"PROP", make([]byte, 0x30)}, {"BINF", make([]byte, 0x10)}, {"PACK", stream}}
+	f.u32(0x10, uint32(4+8*len(sections))).u32(0x14, uint32(len(sections)))
+	offset := 0x14 + 4 + 8*len(sections)
+	for i, section := range sections {
+		f.bytes(0x18+8*i, []byte(section.magic)).u32(0x18+8*i+4, uint32(len(section.data)))
+		f.bytes(offset, []byte(section.magic)).u32(offset+4, uint32(len(section.data))).bytes(offset+8, section.data)
+		offset += 8 + len(section.data)
+	}
+	f.u32(0x04, uint32(len(f.buf)))
+	return f.buf
+}
+
+func Test_parseNUS3(t *testing.T) {
+	info, err := read(t, makeNUS3(makeIDSP(48000, 960000)), "a.nus3bank")
+	assert.NoError(t, err)
+	assert.Equal(t, Info{Format: "NUS3", SampleRate: 48000, Channels: 2, Samples: 960000, Loop: true, LoopStart: 48000, LoopEnd: 960000}, info)
+
+	_, err = read(t, makeNUS3([]byte("OPUS....")), "a.nus3bank")
+	assert.ErrorContains(t, err, "unsupported stream")
+}
+
+func Test_parseIDSP(t *testing.T) {
+	info, err := read(t, makeIDSP(48000, 960000), "a.idsp")
+	assert.NoError(t, err)
+	assert.Equal(t, Info{Format: "IDSP", SampleRate: 48000, Channels: 2, Samples: 960000, Loop: true, LoopStart: 48000, LoopEnd: 960000}, info)
+
+	info, err = read(t, makeIDSP(0, 0), "a.idsp")
+	assert.NoError(t, err)
+	assert.False(t, info.Loop)
+}
@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"errors"
+	"io"
+)
+
+// BRSTM is the stream format of the Wii. The HEAD chunk starts with
+// references to 3 sub-chunks, the first one being the stream info.
+func init() {
+	Register(Parser{Name: "BRSTM", Extensions: []string{".brstm"}, Signature: []byte("RSTM"), Parse: parseRSTM})
+}
+
+func parseRSTM(ra io.ReaderAt, size int64) (Info, error) {
+	r := &reader{r: ra}
+	order, err := byteOrder(r.bytes(0x04, 2))
+	if err != nil {
+		return Info{}, err
+	}
+	r.order = order
+
+	head := int64(r.u32(0x10))
+	if magic := r.bytes(head, 4); r.err != nil {
+		return Info{}, r.err
+	} else if string(magic) != "HEAD" {
+		return Info{}, errors.New("HEAD chunk not found")
+	}
+	stream := head + 0x08 + int64(r.u32(head+0x0c))
+	info := Info{
+		Loop:       r.u8(stream+0x01) != 0,
+		Channels:   int(r.u8(stream + 0x02)),
+		SampleRate: int(r.u16(stream + 0x04)),
+		LoopStart:  int64(r.u32(stream + 0x08)),
+		Samples:    int64(r.u32(stream + 0x0c)),
+	}
+	info.LoopEnd = info.Samples
+	return info, r.err
+}
@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeRSTM(order binary.ByteOrder, loop bool) []byte {
+	f := &fixture{order: order}
+	f.bytes(0x00, []byte("RSTM")).u16(0x04, 0xfeff).u32(0x10, 0x40)
+	// HEAD chunk, the stream info is at 0x40+0x08+0x18
+	f.bytes(0x40, []byte("HEAD")).u32(0x48, 0x01000000).u32(0x4c, 0x18)
+	stream := 0x60
+	if loop {
+		f.u8(stream+0x01, 1)
+	}
+	f.u8(stream+0x00, 2).u8(stream+0x02, 2).u16(stream+0x04, 32000).u32(stream+0x08, 48000).u32(stream+0x0c, 640000)
+	return f.buf
+}
+
+func Test_parseRSTM(t *testing.T) {
+	info, err := read(t, makeRSTM(binary.BigEndian, true), "a.brstm")
+	assert.NoError(t, err)
+	assert.Equal(t, Info{Format: "BRSTM", SampleRate: 32000, Channels: 2, Samples: 640000, Loop: true, LoopStart: 48000, LoopEnd: 640000}, info)
+	assert.Equal(t, 1500000, info.LoopStartMicro())
+	assert.Equal(t, 20.0, info.DurationSec())
+
+	info, err = read(t, makeRSTM(binary.LittleEndian, false), "a.brstm")
+	assert.NoError(t, err)
+	assert.False(t, info.Loop)
+	assert.Equal(t, int64(640000), info.Samples)
+
+	invalid := makeRSTM(binary.BigEndian, true)
+	copy(invalid[0x40:], "XXXX")
+	_, err = read(t, invalid, "a.brstm")
+	assert.ErrorContains(t, err, "HEAD chunk not found")
+}
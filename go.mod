@@ -2,14 +2,18 @@ module vgsgo
 
 go 1.19
 
+require (
+	github.com/maxatome/go-testdeep v1.13.0
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/term v0.13.0
+	golang.org/x/text v0.14.0
+)
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/google/uuid v1.3.0 // indirect
-	github.com/maxatome/go-testdeep v1.13.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/stretchr/testify v1.8.4 // indirect
 	golang.org/x/exp v0.0.0-20230811145659-89c5cff77bcb // indirect
 	golang.org/x/sys v0.14.0 // indirect
-	golang.org/x/term v0.13.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
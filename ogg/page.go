@@ -0,0 +1,206 @@
+// Package ogg reads and writes Ogg pages, and the headers of the Vorbis
+// streams they contain.
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	headerContinued = 0x01
+	headerBOS       = 0x02
+	headerEOS       = 0x04
+)
+
+var capturePattern = []byte("OggS")
+
+const maxPageSize = 27 + 255 + 255*255
+
+// Page is an Ogg page. Segments is its lacing table.
+type Page struct {
+	HeaderType byte
+	Granule    int64
+	Serial     uint32
+	Sequence   uint32
+	Segments   []byte
+	Data       []byte
+}
+
+var crcTable = makeCRCTable()
+
+// makeCRCTable is the table of the CRC of Ogg: polynomial 0x04c11db7, no
+// reflection, initial value and final xor 0.
+func makeCRCTable() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		r := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if r&0x80000000 != 0 {
+				r = r<<1 ^ 0x04c11db7
+			} else {
+				r <<= 1
+			}
+		}
+		table[i] = r
+	}
+	return table
+}
+
+func crc(data []byte) uint32 {
+	var c uint32
+	for _, b := range data {
+		c = c<<8 ^ crcTable[byte(c>>24)^b]
+	}
+	return c
+}
+
+// Bytes encodes the page, with its checksum.
+func (p Page) Bytes() []byte {
+	buf := make([]byte, 27, 27+len(p.Segments)+len(p.Data))
+	copy(buf, capturePattern)
+	buf[5] = p.HeaderType
+	binary.LittleEndian.PutUint64(buf[6:], uint64(p.Granule))
+	binary.LittleEndian.PutUint32(buf[14:], p.Serial)
+	binary.LittleEndian.PutUint32(buf[18:], p.Sequence)
+	buf[26] = byte(len(p.Segments))
+	buf = append(buf, p.Segments...)
+	buf = append(buf, p.Data...)
+	binary.LittleEndian.PutUint32(buf[22:], crc(buf))
+	return buf
+}
+
+// ReadPages decodes all the pages of an Ogg file, checking their checksums.
+func ReadPages(data []byte) ([]Page, error) {
+	pages := make([]Page, 0)
+	for offset := 0; offset < len(data); {
+		page, size, err := readPage(data[offset:])
+		if err != nil {
+			return nil, fmt.Errorf("page at offset %d: %w", offset, err)
+		}
+		pages = append(pages, page)
+		offset += size
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("no ogg page")
+	}
+	return pages, nil
+}
+
+func readPage(data []byte) (Page, int, error) {
+	if len(data) < 27 || !bytes.Equal(data[:4], capturePattern) {
+		return Page{}, 0, errors.New("not an ogg page")
+	}
+	if data[4] != 0 {
+		return Page{}, 0, fmt.Errorf("unsupported version %d", data[4])
+	}
+	segmentCount := int(data[26])
+	if len(data) < 27+segmentCount {
+		return Page{}, 0, errors.New("truncated page")
+	}
+	segments := data[27 : 27+segmentCount]
+	size := 27 + segmentCount
+	for _, s := range segments {
+		size += int(s)
+	}
+	if len(data) < size {
+		return Page{}, 0, errors.New("truncated page")
+	}
+
+	page := Page{
+		HeaderType: data[5],
+		Granule:    int64(binary.LittleEndian.Uint64(data[6:])),
+		Serial:     binary.LittleEndian.Uint32(data[14:]),
+		Sequence:   binary.LittleEndian.Uint32(data[18:]),
+		Segments:   append([]byte{}, segments...),
+		Data:       append([]byte{}, data[27+segmentCount:size]...),
+	}
+	if binary.LittleEndian.Uint32(page.Bytes()[22:]) != binary.LittleEndian.Uint32(data[22:]) {
+		return Page{}, 0, errors.New("invalid checksum")
+	}
+	return page, size, nil
+}
+
+// readPageFrom reads the next page of a stream.
+func readPageFrom(r io.Reader) (Page, error) {
+	header := make([]byte, 27, maxPageSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return Page{}, err
+	}
+	segments := make([]byte, header[26])
+	if _, err := io.ReadFull(r, segments); err != nil {
+		return Page{}, err
+	}
+	size := 0
+	for _, s := range segments {
+		size += int(s)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Page{}, err
+	}
+
+	page, _, err := readPage(append(append(header, segments...), data...))
+	return page, err
+}
+
+// Paginate splits packets of a logical stream into pages, numbered from
+// sequence. The granule position of the pages where a packet ends is
+// granule, -1 for the others.
+func Paginate(packets [][]byte, serial uint32, sequence uint32, granule int64) []Page {
+	pages := make([]Page, 0)
+	page := Page{Serial: serial, Sequence: sequence, Granule: -1}
+	flush := func(continued bool) {
+		pages = append(pages, page)
+		page = Page{Serial: serial, Sequence: page.Sequence + 1, Granule: -1}
+		if continued {
+			page.HeaderType = headerContinued
+		}
+	}
+
+	for _, packet := range packets {
+		remaining := packet
+		for {
+			if len(page.Segments) == 255 {
+				flush(true)
+			}
+			size := len(remaining)
+			if size > 255 {
+				size = 255
+			}
+			page.Segments = append(page.Segments, byte(size))
+			page.Data = append(page.Data, remaining[:size]...)
+			remaining = remaining[size:]
+			if size < 255 {
+				page.Granule = granule
+				break
+			}
+		}
+	}
+	if len(page.Segments) > 0 {
+		flush(false)
+	}
+	return pages
+}
+
+// Packets reassembles the packets of pages of a logical stream. The last
+// packet is not returned if it is not complete.
+func Packets(pages []Page) [][]byte {
+	packets := make([][]byte, 0)
+	var current []byte
+	for _, page := range pages {
+		offset := 0
+		for _, s := range page.Segments {
+			current = append(current, page.Data[offset:offset+int(s)]...)
+			offset += int(s)
+			if s < 255 {
+				packets = append(packets, current)
+				current = nil
+			}
+		}
+	}
+	return packets
+}
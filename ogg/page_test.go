@@ -0,0 +1,72 @@
+package ogg
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_crc(t *testing.T) {
+	assert.Equal(t, uint32(0x89a1897f), crc([]byte("123456789")))
+}
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name         string
+		sizes        []int
+		wantSegments [][]byte
+	}{
+		{"small packets", []int{10, 20}, [][]byte{{10, 20}}},
+		{"255 bytes", []int{255}, [][]byte{{255, 0}}},
+		{"300 bytes", []int{300, 1}, [][]byte{{255, 45, 1}}},
+		{"several pages", []int{255*255 + 10}, [][]byte{bytes.Repeat([]byte{255}, 255), {10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packets := make([][]byte, len(tt.sizes))
+			for i, size := range tt.sizes {
+				packets[i] = bytes.Repeat([]byte{byte(i + 1)}, size)
+			}
+			pages := Paginate(packets, 42, 3, 7)
+
+			segments := make([][]byte, len(pages))
+			for i, page := range pages {
+				segments[i] = page.Segments
+				assert.Equal(t, uint32(42), page.Serial)
+				assert.Equal(t, uint32(3+i), page.Sequence)
+			}
+			assert.Equal(t, tt.wantSegments, segments)
+			assert.Equal(t, packets, Packets(pages))
+		})
+	}
+}
+
+func TestPaginate_continued(t *testing.T) {
+	pages := Paginate([][]byte{make([]byte, 255*255+10)}, 1, 0, 7)
+	assert.Equal(t, byte(0), pages[0].HeaderType)
+	assert.Equal(t, int64(-1), pages[0].Granule)
+	assert.Equal(t, byte(headerContinued), pages[1].HeaderType)
+	assert.Equal(t, int64(7), pages[1].Granule)
+}
+
+func TestReadPages(t *testing.T) {
+	pages := Paginate([][]byte{[]byte("abc"), make([]byte, 255*255)}, 1, 0, 0)
+	data := make([]byte, 0)
+	for _, page := range pages {
+		data = append(data, page.Bytes()...)
+	}
+
+	got, err := ReadPages(data)
+	assert.NoError(t, err)
+	assert.Equal(t, pages, got)
+
+	data[30]++
+	_, err = ReadPages(data)
+	assert.ErrorContains(t, err, "invalid checksum")
+
+	_, err = ReadPages(data[:len(data)-1])
+	assert.Error(t, err)
+
+	_, err = ReadPages([]byte("ID3 not ogg at all............."))
+	assert.Error(t, err)
+}
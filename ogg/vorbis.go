@@ -0,0 +1,278 @@
+package ogg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	vorbisIdentification = 1
+	vorbisComment        = 3
+	vorbisSetup          = 5
+)
+
+// VorbisComments are the comments of a Vorbis stream, as KEY=value strings.
+type VorbisComments struct {
+	Vendor   string
+	Comments []string
+}
+
+// Get is the value of the first comment with the key (case-insensitive).
+func (c VorbisComments) Get(key string) (string, bool) {
+	for _, comment := range c.Comments {
+		if k, v, found := strings.Cut(comment, "="); found && strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the comments with the key (case-insensitive) with a single
+// one, at the position of the first of them.
+func (c *VorbisComments) Set(key, value string) {
+	comments := make([]string, 0, len(c.Comments)+1)
+	set := false
+	for _, comment := range c.Comments {
+		if k, _, found := strings.Cut(comment, "="); found && strings.EqualFold(k, key) {
+			if !set {
+				comments = append(comments, key+"="+value)
+				set = true
+			}
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	if !set {
+		comments = append(comments, key+"="+value)
+	}
+	c.Comments = comments
+}
+
+type VorbisInfo struct {
+	Channels   int
+	SampleRate int
+	// Samples is the length of the stream, from the granule position of its
+	// last page, 0 if unknown.
+	Samples  int64
+	Comments VorbisComments
+}
+
+// ReadVorbis reads the identification and comment headers of the first
+// Vorbis stream of an Ogg file.
+func ReadVorbis(data []byte) (VorbisInfo, error) {
+	pages, err := ReadPages(data)
+	if err != nil {
+		return VorbisInfo{}, err
+	}
+	headers, _, err := vorbisHeaders(pages)
+	if err != nil {
+		return VorbisInfo{}, err
+	}
+	return makeVorbisInfo(headers, lastGranule(pages, pages[0].Serial))
+}
+
+// ReadVorbisFile is like ReadVorbis, but only reads the beginning and the
+// end of the file.
+func ReadVorbisFile(path string) (VorbisInfo, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return VorbisInfo{}, err
+	}
+	defer func() {
+		_ = fh.Close()
+	}()
+
+	r := bufio.NewReader(fh)
+	pages := make([]Page, 0, 3)
+	var headers [][]byte
+	for headers == nil {
+		page, err := readPageFrom(r)
+		if err != nil {
+			return VorbisInfo{}, err
+		}
+		pages = append(pages, page)
+		if len(Packets(pages)) >= 3 {
+			if headers, _, err = vorbisHeaders(pages); err != nil {
+				return VorbisInfo{}, err
+			}
+		}
+	}
+
+	// the last page is in the tail of the file: the maximum size of a page
+	// is 27+255+255*255 bytes
+	stat, err := fh.Stat()
+	if err != nil {
+		return VorbisInfo{}, err
+	}
+	offset := stat.Size() - maxPageSize
+	if offset < 0 {
+		offset = 0
+	}
+	tail := make([]byte, stat.Size()-offset)
+	if _, err := fh.ReadAt(tail, offset); err != nil {
+		return VorbisInfo{}, err
+	}
+	return makeVorbisInfo(headers, lastGranuleInTail(tail, pages[0].Serial))
+}
+
+func makeVorbisInfo(headers [][]byte, samples int64) (VorbisInfo, error) {
+	info := VorbisInfo{
+		Channels:   int(headers[0][11]),
+		SampleRate: int(binary.LittleEndian.Uint32(headers[0][12:])),
+		Samples:    samples,
+	}
+	var err error
+	info.Comments, err = parseComments(headers[1])
+	return info, err
+}
+
+func lastGranule(pages []Page, serial uint32) int64 {
+	for i := len(pages) - 1; i >= 0; i-- {
+		if pages[i].Serial == serial && pages[i].Granule > 0 {
+			return pages[i].Granule
+		}
+	}
+	return 0
+}
+
+// lastGranuleInTail finds the last valid page of the stream in the end of a
+// file, which may start in the middle of a page.
+func lastGranuleInTail(tail []byte, serial uint32) int64 {
+	for i := bytes.LastIndex(tail, capturePattern); i >= 0; i = bytes.LastIndex(tail[:i], capturePattern) {
+		page, _, err := readPage(tail[i:])
+		if err == nil && page.Serial == serial && page.Granule > 0 {
+			return page.Granule
+		}
+	}
+	return 0
+}
+
+// WriteVorbisComments replaces the comment header of the first Vorbis stream
+// of an Ogg file. The pages that follow are renumbered.
+func WriteVorbisComments(data []byte, comments VorbisComments) ([]byte, error) {
+	pages, err := ReadPages(data)
+	if err != nil {
+		return nil, err
+	}
+	headers, lastHeaderPage, err := vorbisHeaders(pages)
+	if err != nil {
+		return nil, err
+	}
+
+	// the identification header is alone on the first page, the comment and
+	// setup headers are on the next ones, the audio starts on a new page
+	serial := pages[0].Serial
+	headerPages := Paginate([][]byte{encodeComments(comments), headers[2]}, serial, pages[0].Sequence+1, 0)
+	shift := int64(len(headerPages)) - int64(lastHeaderPage)
+
+	buf := bytes.NewBuffer(make([]byte, 0, len(data)+1024))
+	buf.Write(pages[0].Bytes())
+	for _, page := range headerPages {
+		buf.Write(page.Bytes())
+	}
+	for _, page := range pages[lastHeaderPage+1:] {
+		if page.Serial == serial {
+			page.Sequence = uint32(int64(page.Sequence) + shift)
+		}
+		buf.Write(page.Bytes())
+	}
+	return buf.Bytes(), nil
+}
+
+// vorbisHeaders are the 3 header packets of the first logical stream, which
+// must be Vorbis, and the index of the page where they end.
+func vorbisHeaders(pages []Page) ([][]byte, int, error) {
+	serial := pages[0].Serial
+	if pages[0].HeaderType&headerBOS == 0 {
+		return nil, 0, errors.New("first page is not the beginning of a stream")
+	}
+	streamPages := make([]Page, 0, 3)
+	for i, page := range pages {
+		if page.Serial != serial {
+			return nil, 0, errors.New("multiplexed streams are not supported")
+		}
+		streamPages = append(streamPages, page)
+		packets := Packets(streamPages)
+		if len(packets) < 3 {
+			continue
+		}
+		if len(packets) > 3 {
+			return nil, 0, errors.New("the audio does not start on a new page")
+		}
+		for j, packetType := range []byte{vorbisIdentification, vorbisComment, vorbisSetup} {
+			if !isVorbisHeader(packets[j], packetType) {
+				return nil, 0, fmt.Errorf("header %d is not a vorbis header", j+1)
+			}
+		}
+		if len(packets[0]) < 16 || len(pages[0].Segments) != 1 {
+			return nil, 0, errors.New("invalid identification header")
+		}
+		return packets, i, nil
+	}
+	return nil, 0, errors.New("vorbis headers not found")
+}
+
+func isVorbisHeader(packet []byte, packetType byte) bool {
+	return len(packet) >= 7 && packet[0] == packetType && string(packet[1:7]) == "vorbis"
+}
+
+func parseComments(packet []byte) (VorbisComments, error) {
+	r := bytes.NewReader(packet[7:])
+	readString := func() (string, error) {
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return "", err
+		}
+		if int64(length) > int64(r.Len()) {
+			return "", errors.New("invalid comment length")
+		}
+		s := make([]byte, length)
+		_, err := r.Read(s)
+		return string(s), err
+	}
+
+	var comments VorbisComments
+	var err error
+	if comments.Vendor, err = readString(); err != nil {
+		return VorbisComments{}, fmt.Errorf("invalid comment header: %w", err)
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return VorbisComments{}, fmt.Errorf("invalid comment header: %w", err)
+	}
+	// each comment takes at least its 4 byte length
+	if int64(count) > int64(r.Len()/4) {
+		return VorbisComments{}, errors.New("invalid comment header: invalid comment count")
+	}
+	comments.Comments = make([]string, 0, count)
+	for i := uint32(0); i < count; i++ {
+		comment, err := readString()
+		if err != nil {
+			return VorbisComments{}, fmt.Errorf("invalid comment header: %w", err)
+		}
+		comments.Comments = append(comments.Comments, comment)
+	}
+	return comments, nil
+}
+
+func encodeComments(comments VorbisComments) []byte {
+	buf := bytes.NewBuffer([]byte{vorbisComment})
+	buf.WriteString("vorbis")
+	writeString := func(s string) {
+		_ = binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+	}
+	writeString(comments.Vendor)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(comments.Comments)))
+	for _, comment := range comments.Comments {
+		writeString(comment)
+	}
+	// framing bit
+	buf.WriteByte(1)
+	return buf.Bytes()
+}
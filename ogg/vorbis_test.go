@@ -0,0 +1,126 @@
+package ogg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeVorbisFile makes an Ogg Vorbis file, with fake setup header and audio.
+func makeVorbisFile(sampleRate int, comments VorbisComments) []byte {
+	identification := make([]byte, 30)
+	identification[0] = vorbisIdentification
+	copy(identification[1:], "vorbis")
+	identification[11] = 2
+	binary.LittleEndian.PutUint32(identification[12:], uint32(sampleRate))
+	setup := append([]byte{vorbisSetup}, []byte("vorbis")...)
+	setup = append(setup, bytes.Repeat([]byte{0x55}, 3000)...)
+
+	first := Paginate([][]byte{identification}, 1234, 0, 0)[0]
+	first.HeaderType = headerBOS
+	pages := []Page{first}
+	pages = append(pages, Paginate([][]byte{encodeComments(comments), setup}, 1234, 1, 0)...)
+	audio := Paginate([][]byte{bytes.Repeat([]byte{1}, 70000), {2, 3}}, 1234, uint32(len(pages)), 44100)
+	audio[len(audio)-1].HeaderType |= headerEOS
+	pages = append(pages, audio...)
+
+	data := make([]byte, 0)
+	for _, page := range pages {
+		data = append(data, page.Bytes()...)
+	}
+	return data
+}
+
+func TestReadVorbis(t *testing.T) {
+	comments := VorbisComments{Vendor: "test", Comments: []string{"TITLE=Hyrule Field", "LoopStart=10"}}
+	info, err := ReadVorbis(makeVorbisFile(32000, comments))
+	assert.NoError(t, err)
+	assert.Equal(t, VorbisInfo{Channels: 2, SampleRate: 32000, Samples: 44100, Comments: comments}, info)
+
+	value, found := info.Comments.Get("LOOPSTART")
+	assert.True(t, found)
+	assert.Equal(t, "10", value)
+	_, found = info.Comments.Get("LOOPLENGTH")
+	assert.False(t, found)
+}
+
+func TestReadVorbisFile(t *testing.T) {
+	comments := VorbisComments{Vendor: "test", Comments: []string{"TITLE=Hyrule Field"}}
+	data := makeVorbisFile(48000, comments)
+	assert.Greater(t, len(data), maxPageSize)
+	path := filepath.Join(t.TempDir(), "a.ogg")
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+
+	info, err := ReadVorbisFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, VorbisInfo{Channels: 2, SampleRate: 48000, Samples: 44100, Comments: comments}, info)
+
+	assert.NoError(t, os.WriteFile(path, []byte("not an ogg file, but long enough"), 0600))
+	_, err = ReadVorbisFile(path)
+	assert.Error(t, err)
+
+	_, err = ReadVorbisFile(filepath.Join(t.TempDir(), "missing.ogg"))
+	assert.Error(t, err)
+}
+
+func Test_parseComments_invalidCount(t *testing.T) {
+	packet := encodeComments(VorbisComments{Vendor: "test"})
+	// the count follows the type, "vorbis", the vendor length and the vendor
+	binary.LittleEndian.PutUint32(packet[7+4+len("test"):], 0xffffffff)
+	_, err := parseComments(packet)
+	assert.Error(t, err)
+}
+
+func TestWriteVorbisComments(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []string
+	}{
+		{"fewer pages", []string{}},
+		{"same pages", []string{"TITLE=a", "LOOPSTART=1", "LOOPLENGTH=2"}},
+		{"more pages", []string{"LYRICS=" + string(bytes.Repeat([]byte("la"), 40000))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := makeVorbisFile(44100, VorbisComments{Vendor: "test", Comments: []string{"TITLE=Hyrule Field"}})
+			comments := VorbisComments{Vendor: "new", Comments: tt.comments}
+			written, err := WriteVorbisComments(data, comments)
+			assert.NoError(t, err)
+
+			info, err := ReadVorbis(written)
+			assert.NoError(t, err)
+			assert.Equal(t, comments, info.Comments)
+			assert.Equal(t, 44100, info.SampleRate)
+
+			// the pages are numbered in order and the audio is unchanged
+			pages, err := ReadPages(written)
+			assert.NoError(t, err)
+			for i, page := range pages {
+				assert.Equal(t, uint32(i), page.Sequence)
+			}
+			original, _ := ReadPages(data)
+			assert.Equal(t, Packets(original)[3:], Packets(pages)[3:])
+			assert.NotZero(t, pages[len(pages)-1].HeaderType&headerEOS)
+		})
+	}
+}
+
+func TestWriteVorbisComments_errors(t *testing.T) {
+	notVorbis := Paginate([][]byte{[]byte("OpusHead"), []byte("OpusTags"), []byte("audio")}, 1, 0, 0)
+	notVorbis[0].HeaderType = headerBOS
+	_, err := WriteVorbisComments(notVorbis[0].Bytes(), VorbisComments{})
+	assert.Error(t, err)
+
+	_, err = WriteVorbisComments([]byte("not an ogg file"), VorbisComments{})
+	assert.Error(t, err)
+}
+
+func TestVorbisComments_Set(t *testing.T) {
+	c := VorbisComments{Comments: []string{"TITLE=a", "loopstart=1", "ARTIST=b", "LOOPSTART=2"}}
+	c.Set("LOOPSTART", "3")
+	c.Set("LOOPLENGTH", "4")
+	assert.Equal(t, []string{"TITLE=a", "LOOPSTART=3", "ARTIST=b", "LOOPLENGTH=4"}, c.Comments)
+}
@@ -0,0 +1,157 @@
+package player
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EndReason is how the playback of a song ended.
+type EndReason int
+
+const (
+	// EndFinished is the natural end of the song.
+	EndFinished EndReason = iota
+	// EndSkipped is a song stopped to play the next one.
+	EndSkipped
+	// EndQuit is a song stopped to quit.
+	EndQuit
+	// EndError is a player that failed.
+	EndError
+)
+
+var endReasonNames = map[EndReason]string{
+	EndFinished: "finished",
+	EndSkipped:  "skipped",
+	EndQuit:     "quit",
+	EndError:    "error",
+}
+
+func (e EndReason) String() string {
+	return endReasonNames[e]
+}
+
+// seekStepSec is how far the f and b controls seek without a number.
+const seekStepSec = 10
+
+// Controls reads the lines typed by the user in the background, so that
+// they can be read as controls while a song plays, with mplayer in slave
+// mode, and as answers to the prompts otherwise: it is also the Input of the
+// player.
+type Controls struct {
+	lines chan string
+	rest  []byte
+}
+
+// ReadControls starts reading the lines of r.
+func ReadControls(r io.Reader) *Controls {
+	c := &Controls{lines: make(chan string)}
+	go func() {
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			c.lines <- scanner.Text()
+		}
+		close(c.lines)
+	}()
+	return c
+}
+
+// Read reads at most one line, so that the lines read by a bufio.Scanner and
+// not used are not lost.
+func (c *Controls) Read(p []byte) (int, error) {
+	if len(c.rest) == 0 {
+		line, ok := <-c.lines
+		if !ok {
+			return 0, io.EOF
+		}
+		c.rest = []byte(line + "\n")
+	}
+	n := copy(p, c.rest)
+	c.rest = c.rest[n:]
+	return n, nil
+}
+
+// slaveCommand converts a control to the mplayer slave command, and tells
+// whether it ends the song. Unknown controls give an empty command.
+func slaveCommand(control string) (string, EndReason, bool) {
+	fields := strings.Fields(control)
+	if len(fields) == 0 {
+		return "", EndFinished, false
+	}
+	switch strings.ToLower(fields[0]) {
+	case "p":
+		return "pause", EndFinished, false
+	case "f", "b":
+		seconds := seekStepSec
+		if len(fields) > 1 {
+			if n, err := strconv.Atoi(fields[1]); err == nil {
+				seconds = n
+			}
+		}
+		if strings.ToLower(fields[0]) == "b" {
+			seconds = -seconds
+		}
+		return fmt.Sprintf("seek %d 0", seconds), EndFinished, false
+	case "s":
+		return "quit", EndSkipped, true
+	case "q":
+		return "quit", EndQuit, true
+	}
+	return "", EndFinished, false
+}
+
+// execControlled runs mplayer in slave mode, sending it the controls typed
+// by the user, until it exits.
+func (p Player) execControlled(args []string) EndReason {
+	r, w, err := os.Pipe()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	args = append([]string{args[0], "-slave", "-quiet"}, args[1:]...)
+	proc, err := os.StartProcess(p.Cmd, args, &os.ProcAttr{
+		Env:   os.Environ(),
+		Files: []*os.File{r, os.Stdout, os.Stderr},
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	_ = r.Close()
+	defer func() {
+		_ = w.Close()
+	}()
+
+	done := make(chan bool)
+	go func() {
+		done <- wait(proc).Success()
+	}()
+
+	end := EndFinished
+	lines := p.Controls.lines
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				// no more controls, the song plays until its end
+				lines = nil
+				continue
+			}
+			command, reason, ends := slaveCommand(line)
+			if command == "" {
+				continue
+			}
+			if ends {
+				end = reason
+			}
+			_, _ = fmt.Fprintln(w, command)
+		case success := <-done:
+			if !success && end == EndFinished {
+				return EndError
+			}
+			return end
+		}
+	}
+}
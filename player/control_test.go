@@ -0,0 +1,89 @@
+package player
+
+import (
+	"bufio"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"vgsgo/songrep"
+)
+
+func Test_slaveCommand(t *testing.T) {
+	tests := []struct {
+		control     string
+		wantCommand string
+		wantReason  EndReason
+		wantEnds    bool
+	}{
+		{"p", "pause", EndFinished, false},
+		{"f", "seek 10 0", EndFinished, false},
+		{"B 30", "seek -30 0", EndFinished, false},
+		{"s", "quit", EndSkipped, true},
+		{" q ", "quit", EndQuit, true},
+		{"", "", EndFinished, false},
+		{"x", "", EndFinished, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.control, func(t *testing.T) {
+			command, reason, ends := slaveCommand(tt.control)
+			assert.Equal(t, tt.wantCommand, command)
+			assert.Equal(t, tt.wantReason, reason)
+			assert.Equal(t, tt.wantEnds, ends)
+		})
+	}
+}
+
+func TestControls_Read(t *testing.T) {
+	c := ReadControls(strings.NewReader("\n3 r\n"))
+	assert.Equal(t, RatingAction{Value: 0}, Player{Input: c, Output: &strings.Builder{}}.Rate())
+	assert.Equal(t, RatingAction{Value: 3, Resume: true}, Player{Input: c, Output: &strings.Builder{}}.Rate())
+
+	scanner := bufio.NewScanner(c)
+	assert.False(t, scanner.Scan())
+}
+
+// fakePlayer writes a script that behaves like mplayer in slave mode: it
+// writes the commands it receives to a file until quit, or exits at once
+// with the status, like at the end of a song.
+func fakePlayer(t *testing.T, status string) (string, string) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "mplayer")
+	log := filepath.Join(dir, "commands")
+	content := "#!/bin/sh\nexit " + status + "\n"
+	if status == "" {
+		content = "#!/bin/sh\nwhile read cmd; do\n  echo \"$cmd\" >> " + log + "\n  [ \"$cmd\" = quit ] && exit 0\ndone\n"
+	}
+	assert.NoError(t, os.WriteFile(script, []byte(content), 0700))
+	return script, log
+}
+
+func TestPlayer_Play_controls(t *testing.T) {
+	tests := []struct {
+		name         string
+		controls     string
+		status       string
+		wantEnd      EndReason
+		wantSkipped  bool
+		wantCommands string
+	}{
+		{"natural end", "", "0", EndFinished, false, ""},
+		{"skip", "p\nf\ns\n", "", EndSkipped, true, "pause\nseek 10 0\nquit\n"},
+		{"quit", "x\nq\n", "", EndQuit, false, "quit\n"},
+		{"error", "", "3", EndError, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script, log := fakePlayer(t, tt.status)
+			p := Player{Cmd: script, MaxPlays: 1, Controls: ReadControls(strings.NewReader(tt.controls))}
+			// short enough for the fake player not to be considered as skipped
+			report := p.Play(songrep.Song{AbsPath: "/foo/bar.brstm", DurationSec: 1})
+
+			assert.Equal(t, tt.wantEnd, report.End)
+			assert.Equal(t, tt.wantSkipped, report.Skipped)
+			commands, _ := os.ReadFile(log)
+			assert.Equal(t, tt.wantCommands, string(commands))
+		})
+	}
+}
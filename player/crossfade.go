@@ -0,0 +1,75 @@
+package player
+
+import (
+	"math"
+	"time"
+	"vgsgo/songrep"
+)
+
+// Playback is a song being played in the background, see Player.Start.
+type Playback struct {
+	Song   songrep.Song
+	player Player
+	start  time.Time
+	done   chan struct{}
+	end    EndReason
+}
+
+// Start plays a song without waiting for its end, so that the next song can
+// be started while it fades out. The song fades in and out over
+// CrossfadeSec, unless the fades of the player are longer.
+func (p Player) Start(song songrep.Song) *Playback {
+	player := p
+	if player.FadeInSec < p.CrossfadeSec {
+		player.FadeInSec = p.CrossfadeSec
+	}
+	if player.FadeOutSec < p.CrossfadeSec {
+		player.FadeOutSec = p.CrossfadeSec
+	}
+	if p.CheckMetadata {
+		p.checkMetadata(song)
+	}
+
+	proc := p.startProcess(player.getArgs(song))
+	b := &Playback{Song: song, player: p, start: time.Now(), done: make(chan struct{})}
+	go func() {
+		if !wait(proc).Success() {
+			b.end = EndError
+		}
+		close(b.done)
+	}()
+	return b
+}
+
+// WaitCrossfade waits until the next song must be started: CrossfadeSec
+// before the expected end of the song, or its end if it is stopped before.
+func (b *Playback) WaitCrossfade() {
+	timer := time.NewTimer(time.Until(b.start.Add(b.crossfadeDelay())))
+	defer timer.Stop()
+	select {
+	case <-b.done:
+	case <-timer.C:
+	}
+}
+
+// crossfadeDelay is when the next song starts, from the start of this one.
+// A song that loops indefinitely has no expected end, it is never crossfaded.
+func (b *Playback) crossfadeDelay() time.Duration {
+	if b.player.MaxPlayTimeSec == 0 && b.player.MaxPlays == 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	delaySec := b.player.expectedDurationSec(b.Song) - b.player.CrossfadeSec
+	if delaySec < 0 {
+		delaySec = 0
+	}
+	return time.Duration(float64(delaySec) * float64(time.Second))
+}
+
+// Wait waits for the end of the song and reports how it has been listened
+// to, like Play.
+func (b *Playback) Wait() PlayReport {
+	<-b.done
+	report := b.player.makeReport(b.Song, float32(time.Since(b.start).Seconds()))
+	report.End = b.end
+	return report
+}
@@ -0,0 +1,47 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+	"vgsgo/songrep"
+)
+
+func TestPlayback_crossfadeDelay(t *testing.T) {
+	song := songrep.Song{DurationSec: 10, LoopStartMicro: 2000000, LoopEndMicro: 8000000}
+	tests := []struct {
+		name   string
+		player Player
+		want   time.Duration
+	}{
+		{"max plays", Player{MaxPlays: 2, CrossfadeSec: 3}, 11 * time.Second},
+		{"max play time", Player{MaxPlayTimeSec: 30, CrossfadeSec: 2.5}, 27500 * time.Millisecond},
+		{"crossfade longer than the song", Player{MaxPlays: 1, CrossfadeSec: 20}, 0},
+		{"infinite loop", Player{CrossfadeSec: 3}, time.Duration(math.MaxInt64)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := Playback{Song: song, player: tt.player}
+			assert.Equal(t, tt.want, b.crossfadeDelay())
+		})
+	}
+}
+
+func TestPlayer_Start(t *testing.T) {
+	// the song ends (the command exits) long before the crossfade
+	p := Player{Cmd: "/bin/true", MaxPlays: 1, CrossfadeSec: 1}
+	b := p.Start(songrep.Song{AbsPath: "/foo/bar.brstm", DurationSec: 60})
+
+	waited := make(chan struct{})
+	go func() {
+		b.WaitCrossfade()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+	case <-time.After(10 * time.Second):
+		t.Fatal("WaitCrossfade didn't return at the end of the song")
+	}
+	assert.True(t, b.Wait().Skipped)
+}
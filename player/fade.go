@@ -0,0 +1,190 @@
+package player
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"vgsgo/songrep"
+)
+
+// mplayer can't change the volume while playing a file, so a fade is made
+// of short steps of the song, each one played at a lower (or higher) volume
+// with -af volume.
+const (
+	minFadeStepSec = 0.5
+	maxFadeSteps   = 10
+)
+
+// entry is a file of the mplayer command line with its options: it plays
+// from startSec to endSec (0 for the end of the file), loops times, with its
+// volume changed by volumeDB.
+type entry struct {
+	options  []string
+	startSec float32
+	endSec   float32
+	loops    int
+	volumeDB float64
+}
+
+// withVolume adds the fade-in to the first entry of the arguments, and the
+// fade-out to the end of the last one, unless it loops forever. The gain is
+// applied to all the entries.
+func (p Player) withVolume(song songrep.Song, args []string, gainDB float64) []string {
+	entries := splitEntries(song.AbsPath, args[1:])
+	if len(entries) == 0 {
+		return args
+	}
+
+	faded := make([]entry, 0, len(entries)+2*maxFadeSteps)
+	for i, e := range entries {
+		fadeIn := float32(0)
+		if i == 0 {
+			fadeIn = p.FadeInSec
+		}
+		fadeOut := float32(0)
+		if i == len(entries)-1 && e.loops != 0 {
+			fadeOut = p.FadeOutSec
+			if e.loops > 1 {
+				// the previous loops are played as they are
+				faded = append(faded, entry{startSec: e.startSec, endSec: e.endSec, loops: e.loops - 1})
+				e.loops = 1
+			}
+		}
+		if fadeIn == 0 && fadeOut == 0 {
+			faded = append(faded, e)
+			continue
+		}
+		faded = append(faded, fadeEntry(e, song.DurationSec, fadeIn, fadeOut)...)
+	}
+
+	rv := []string{args[0]}
+	for _, e := range faded {
+		e.volumeDB += gainDB
+		rv = append(rv, song.AbsPath)
+		if e.options != nil {
+			rv = append(rv, e.options...)
+			rv = append(rv, e.volumeArgs()...)
+		} else {
+			rv = append(rv, e.args()...)
+		}
+	}
+	return rv
+}
+
+// splitEntries parses the arguments made by getArgsWithMaxPlays and
+// getArgsWithMaxPlayTime. An entry without -loop is played once.
+func splitEntries(path string, args []string) []entry {
+	entries := make([]entry, 0)
+	for i := 0; i < len(args); i++ {
+		if args[i] == path {
+			entries = append(entries, entry{options: []string{}, loops: 1})
+			continue
+		}
+		if len(entries) == 0 || i+1 >= len(args) {
+			continue
+		}
+		e := &entries[len(entries)-1]
+		e.options = append(e.options, args[i], args[i+1])
+		value, _ := strconv.ParseFloat(args[i+1], 32)
+		switch args[i] {
+		case "-ss":
+			e.startSec = float32(value)
+		case "-endpos":
+			e.endSec = float32(value)
+		case "-loop":
+			e.loops = int(value)
+		}
+		i++
+	}
+	return entries
+}
+
+// fadeEntry splits an entry played once into the fade-in steps, the middle
+// of the entry and the fade-out steps.
+func fadeEntry(e entry, durationSec, fadeInSec, fadeOutSec float32) []entry {
+	endSec := e.endSec
+	if endSec == 0 {
+		endSec = durationSec
+	}
+	length := endSec - e.startSec
+	if length <= 0 {
+		// the end of the file is unknown, only the fade-in is possible
+		fadeOutSec = 0
+		length = float32(math.MaxFloat32)
+	}
+	if fadeInSec+fadeOutSec > length {
+		ratio := length / (fadeInSec + fadeOutSec)
+		fadeInSec *= ratio
+		fadeOutSec *= ratio
+	}
+
+	entries := make([]entry, 0, 2*maxFadeSteps+1)
+	middleStart := e.startSec + fadeInSec
+	steps := fadeSteps(fadeInSec)
+	for i := 0; i < steps; i++ {
+		entries = append(entries, entry{
+			startSec: e.startSec + fadeInSec*float32(i)/float32(steps),
+			endSec:   e.startSec + fadeInSec*float32(i+1)/float32(steps),
+			loops:    1,
+		}.atGain(float64(i+1)/float64(steps+1)))
+	}
+
+	middleEnd := e.endSec
+	if fadeOutSec > 0 {
+		middleEnd = endSec - fadeOutSec
+	}
+	if fadeOutSec == 0 || middleEnd > middleStart {
+		entries = append(entries, entry{startSec: middleStart, endSec: middleEnd, loops: 1})
+	}
+
+	steps = fadeSteps(fadeOutSec)
+	for i := 0; i < steps; i++ {
+		entries = append(entries, entry{
+			startSec: middleEnd + fadeOutSec*float32(i)/float32(steps),
+			endSec:   middleEnd + fadeOutSec*float32(i+1)/float32(steps),
+			loops:    1,
+		}.atGain(float64(steps-i)/float64(steps+1)))
+	}
+	return entries
+}
+
+func fadeSteps(fadeSec float32) int {
+	if fadeSec <= 0 {
+		return 0
+	}
+	steps := int(fadeSec / minFadeStepSec)
+	if steps < 1 {
+		return 1
+	}
+	if steps > maxFadeSteps {
+		return maxFadeSteps
+	}
+	return steps
+}
+
+// atGain plays the entry at a fraction of the volume.
+func (e entry) atGain(gain float64) entry {
+	e.volumeDB = 20 * math.Log10(gain)
+	return e
+}
+
+func (e entry) volumeArgs() []string {
+	if e.volumeDB == 0 {
+		return nil
+	}
+	return []string{"-af", fmt.Sprintf("volume=%.1f", e.volumeDB)}
+}
+
+func (e entry) args() []string {
+	args := make([]string, 0, 8)
+	if e.startSec != 0 {
+		args = append(args, "-ss", fmt.Sprintf("%f", e.startSec))
+	}
+	if e.endSec != 0 {
+		args = append(args, "-endpos", fmt.Sprintf("%f", e.endSec))
+	}
+	if e.loops != 1 {
+		args = append(args, "-loop", strconv.Itoa(e.loops))
+	}
+	return append(args, e.volumeArgs()...)
+}
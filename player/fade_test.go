@@ -0,0 +1,76 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vgsgo/songrep"
+)
+
+func TestPlayer_withVolume(t *testing.T) {
+	const path = "/foo/bar.brstm"
+	looped := songrep.Song{AbsPath: path, DurationSec: 10, LoopStartMicro: 1500000, LoopEndMicro: 7500000}
+	tests := []struct {
+		name   string
+		player Player
+		song   songrep.Song
+		gainDB float64
+		want   []string
+	}{
+		{"fade-out of the last loop", Player{MaxPlays: 2, FadeOutSec: 2}, looped, 0, []string{
+			"mplayer", path, "-endpos", "7.500000",
+			path, "-ss", "1.500000", "-endpos", "5.500000",
+			path, "-ss", "5.500000", "-endpos", "6.000000", "-af", "volume=-1.9",
+			path, "-ss", "6.000000", "-endpos", "6.500000", "-af", "volume=-4.4",
+			path, "-ss", "6.500000", "-endpos", "7.000000", "-af", "volume=-8.0",
+			path, "-ss", "7.000000", "-endpos", "7.500000", "-af", "volume=-14.0",
+		}},
+		{"previous loops are kept", Player{MaxPlays: 3, FadeOutSec: 1}, looped, 0, []string{
+			"mplayer", path, "-endpos", "7.500000",
+			path, "-ss", "1.500000", "-endpos", "7.500000",
+			path, "-ss", "1.500000", "-endpos", "6.500000",
+			path, "-ss", "6.500000", "-endpos", "7.000000", "-af", "volume=-3.5",
+			path, "-ss", "7.000000", "-endpos", "7.500000", "-af", "volume=-9.5",
+		}},
+		{"fade-in, no fade-out of an infinite loop", Player{FadeInSec: 1, FadeOutSec: 2}, looped, 0, []string{
+			"mplayer", path, "-endpos", "0.500000", "-af", "volume=-9.5",
+			path, "-ss", "0.500000", "-endpos", "1.000000", "-af", "volume=-3.5",
+			path, "-ss", "1.000000", "-endpos", "7.500000",
+			path, "-ss", "1.500000", "-endpos", "7.500000", "-loop", "0",
+		}},
+		{"fade-in and fade-out of a single play", Player{MaxPlays: 1, FadeInSec: 0.5, FadeOutSec: 0.5}, looped, 0, []string{
+			"mplayer", path, "-endpos", "0.500000", "-af", "volume=-6.0",
+			path, "-ss", "0.500000", "-endpos", "7.000000",
+			path, "-ss", "7.000000", "-endpos", "7.500000", "-af", "volume=-6.0",
+		}},
+		{"max play time", Player{MaxPlayTimeSec: 5, FadeOutSec: 1}, songrep.Song{AbsPath: path, DurationSec: 10}, 0, []string{
+			"mplayer", path, "-endpos", "4.000000",
+			path, "-ss", "4.000000", "-endpos", "4.500000", "-af", "volume=-3.5",
+			path, "-ss", "4.500000", "-endpos", "5.000000", "-af", "volume=-9.5",
+		}},
+		{"fades longer than the song", Player{MaxPlays: 1, FadeInSec: 4, FadeOutSec: 4}, songrep.Song{AbsPath: path, DurationSec: 1}, 0, []string{
+			"mplayer", path, "-endpos", "0.500000", "-af", "volume=-6.0",
+			path, "-ss", "0.500000", "-endpos", "1.000000", "-af", "volume=-6.0",
+		}},
+		{"gain", Player{MaxPlays: 2}, looped, -4.56, []string{
+			"mplayer", path, "-endpos", "7.500000", "-af", "volume=-4.6",
+			path, "-ss", "1.500000", "-endpos", "7.500000", "-loop", "1", "-af", "volume=-4.6",
+		}},
+		{"gain and fade-out", Player{MaxPlays: 1, FadeOutSec: 0.5}, looped, 3, []string{
+			"mplayer", path, "-endpos", "7.000000", "-af", "volume=3.0",
+			path, "-ss", "7.000000", "-endpos", "7.500000", "-af", "volume=-3.0",
+		}},
+		{"unknown duration", Player{MaxPlays: 1, FadeOutSec: 2}, songrep.Song{AbsPath: path}, 0, []string{"mplayer", path}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.player.Cmd = "mplayer"
+			var args []string
+			if tt.player.MaxPlayTimeSec != 0 {
+				args = tt.player.getArgsWithMaxPlayTime(tt.song)
+			} else {
+				args = tt.player.getArgsWithMaxPlays(tt.song)
+			}
+			assert.Equal(t, tt.want, tt.player.withVolume(tt.song, args, tt.gainDB))
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package player
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"vgsgo/songrep"
+)
+
+// PlayLoopBoundary plays the last seconds of a song before its loop end,
+// followed by the first seconds after its loop start, repeat times: a good
+// loop can't be heard.
+func (p Player) PlayLoopBoundary(song songrep.Song, seconds float32, repeat int) {
+	p.exec(p.getLoopBoundaryArgs(song, seconds, repeat))
+}
+
+func (p Player) getLoopBoundaryArgs(song songrep.Song, seconds float32, repeat int) []string {
+	loopStartSec := float32(song.LoopStartMicro) / 1000000.0
+	loopEndSec := song.DurationSec
+	if song.LoopEndMicro != 0 {
+		loopEndSec = float32(song.LoopEndMicro) / 1000000.0
+	}
+	beforeEndSec := loopEndSec - seconds
+	if beforeEndSec < 0 {
+		beforeEndSec = 0
+	}
+
+	args := make([]string, 0, 1+repeat*12)
+	args = append(args, p.Cmd)
+	for i := 0; i < repeat; i++ {
+		args = append(args, song.AbsPath)
+		if beforeEndSec != 0 {
+			args = append(args, "-ss", fmt.Sprintf("%f", beforeEndSec))
+		}
+		if song.LoopEndMicro != 0 {
+			args = append(args, "-endpos", fmt.Sprintf("%f", loopEndSec))
+		}
+		args = append(args, song.AbsPath)
+		if loopStartSec != 0 {
+			args = append(args, "-ss", fmt.Sprintf("%f", loopStartSec))
+		}
+		args = append(args, "-endpos", fmt.Sprintf("%f", loopStartSec+seconds))
+	}
+	return args
+}
+
+// LoopAction is what to do after a loop boundary has been played: move the
+// loop points by some milliseconds and play it again, save them, or quit.
+type LoopAction struct {
+	StartMs int
+	EndMs   int
+	Save    bool
+	Quit    bool
+}
+
+// AskLoopAction asks how to move the loop points, like "s+10 e-5" to move
+// the loop start 10ms later and the loop end 5ms earlier. Without a number,
+// the points are moved by stepMs.
+func (p Player) AskLoopAction(stepMs int) LoopAction {
+	pat := regexp.MustCompile(`^(?i:([se])([+-])(\d*)|(w)|(q))$`)
+	scanner := bufio.NewScanner(p.Input)
+	for {
+		_, err := fmt.Fprintf(p.Output, "Move the loop points ([s|e]+|-[<ms>] ...), replay (empty), save (w) or quit (q)? ")
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if !scanner.Scan() {
+			return LoopAction{Quit: true}
+		}
+
+		action, valid := LoopAction{}, true
+		for _, field := range strings.Fields(scanner.Text()) {
+			matches := pat.FindStringSubmatch(field)
+			if matches == nil {
+				valid = false
+				break
+			}
+			switch {
+			case matches[4] != "":
+				action.Save = true
+			case matches[5] != "":
+				action.Quit = true
+			default:
+				ms := stepMs
+				if matches[3] != "" {
+					ms, _ = strconv.Atoi(matches[3])
+				}
+				if matches[2] == "-" {
+					ms = -ms
+				}
+				if strings.EqualFold(matches[1], "s") {
+					action.StartMs += ms
+				} else {
+					action.EndMs += ms
+				}
+			}
+		}
+		if valid {
+			return action
+		}
+	}
+}
+
+// Apply moves the loop points of a song. A song that loops at the end of
+// the file gets an explicit loop end, and the loop start stays within the
+// song.
+func (a LoopAction) Apply(song songrep.Song) songrep.Song {
+	if a.EndMs != 0 {
+		if song.LoopEndMicro == 0 {
+			song.LoopEndMicro = int(song.DurationSec * 1000000)
+		}
+		song.LoopEndMicro += a.EndMs * 1000
+	}
+	song.LoopStartMicro += a.StartMs * 1000
+	if song.LoopStartMicro < 0 {
+		song.LoopStartMicro = 0
+	}
+	if song.LoopEndMicro != 0 && song.LoopEndMicro <= song.LoopStartMicro {
+		song.LoopEndMicro = song.LoopStartMicro + 1000
+	}
+	return song
+}
@@ -0,0 +1,76 @@
+package player
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"vgsgo/songrep"
+)
+
+func TestPlayer_getLoopBoundaryArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		song   songrep.Song
+		repeat int
+		want   []string
+	}{
+		{"loop start and end", songrep.Song{AbsPath: "/a.brstm", DurationSec: 60, LoopStartMicro: 10000000, LoopEndMicro: 50000000}, 1, []string{
+			"mplayer", "/a.brstm", "-ss", "47.000000", "-endpos", "50.000000", "/a.brstm", "-ss", "10.000000", "-endpos", "13.000000",
+		}},
+		{"loop at the end of the file, repeated", songrep.Song{AbsPath: "/a.brstm", DurationSec: 60, LoopStartMicro: 10000000}, 2, []string{
+			"mplayer", "/a.brstm", "-ss", "57.000000", "/a.brstm", "-ss", "10.000000", "-endpos", "13.000000",
+			"/a.brstm", "-ss", "57.000000", "/a.brstm", "-ss", "10.000000", "-endpos", "13.000000",
+		}},
+		{"short song", songrep.Song{AbsPath: "/a.brstm", DurationSec: 2, LoopEndMicro: 2000000}, 1, []string{
+			"mplayer", "/a.brstm", "-endpos", "2.000000", "/a.brstm", "-endpos", "3.000000",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Player{Cmd: "mplayer"}.getLoopBoundaryArgs(tt.song, 3, tt.repeat))
+		})
+	}
+}
+
+func TestPlayer_AskLoopAction(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  LoopAction
+	}{
+		{"replay", "\n", LoopAction{}},
+		{"start later", "s+25\n", LoopAction{StartMs: 25}},
+		{"both, default step", "S- e+\n", LoopAction{StartMs: -10, EndMs: 10}},
+		{"added", "e+5 e+5\n", LoopAction{EndMs: 10}},
+		{"invalid, then save", "x+5\nw\n", LoopAction{Save: true}},
+		{"quit", "q\n", LoopAction{Quit: true}},
+		{"end of input", "", LoopAction{Quit: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Player{Input: strings.NewReader(tt.input), Output: bytes.NewBuffer([]byte{})}
+			assert.Equal(t, tt.want, p.AskLoopAction(10))
+		})
+	}
+}
+
+func TestLoopAction_Apply(t *testing.T) {
+	song := songrep.Song{DurationSec: 60, LoopStartMicro: 10000000, LoopEndMicro: 50000000}
+	tests := []struct {
+		name   string
+		action LoopAction
+		song   songrep.Song
+		want   songrep.Song
+	}{
+		{"moved", LoopAction{StartMs: 25, EndMs: -10}, song, songrep.Song{DurationSec: 60, LoopStartMicro: 10025000, LoopEndMicro: 49990000}},
+		{"loop at the end of the file", LoopAction{EndMs: -5}, songrep.Song{DurationSec: 60}, songrep.Song{DurationSec: 60, LoopEndMicro: 59995000}},
+		{"start before the file", LoopAction{StartMs: -20000}, song, songrep.Song{DurationSec: 60, LoopEndMicro: 50000000}},
+		{"end before the start", LoopAction{EndMs: -45000}, song, songrep.Song{DurationSec: 60, LoopStartMicro: 10000000, LoopEndMicro: 10001000}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.action.Apply(tt.song))
+		})
+	}
+}
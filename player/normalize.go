@@ -0,0 +1,57 @@
+package player
+
+import (
+	"fmt"
+	"vgsgo/songrep"
+)
+
+// Normalization is how the loudness of the songs is normalized.
+type Normalization int
+
+const (
+	// NormalizeOff plays the songs at the volume of their files.
+	NormalizeOff Normalization = iota
+	// NormalizeTrack gives all the songs the same loudness.
+	NormalizeTrack
+	// NormalizeGame gives all the games the same loudness, keeping the
+	// differences between the songs of a game.
+	NormalizeGame
+)
+
+var normalizationNames = map[Normalization]string{
+	NormalizeOff:   "off",
+	NormalizeTrack: "track",
+	NormalizeGame:  "game",
+}
+
+func (n Normalization) String() string {
+	return normalizationNames[n]
+}
+
+func (n Normalization) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+func (n *Normalization) UnmarshalText(text []byte) error {
+	for normalization, name := range normalizationNames {
+		if name == string(text) {
+			*n = normalization
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown normalization %q (must be track, game or off)", string(text))
+}
+
+// gainDB is the gain applied to a song. Songs whose gain is unknown are
+// played as they are.
+func (p Player) gainDB(song songrep.Song) float64 {
+	switch p.Normalize {
+	case NormalizeTrack:
+		return float64(song.GainDB)
+	case NormalizeGame:
+		if song.Game != nil {
+			return float64(song.Game.GainDB)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,34 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"vgsgo/songrep"
+)
+
+func TestPlayer_gainDB(t *testing.T) {
+	game := songrep.Game{Title: "A", GainDB: -2}
+	song := songrep.Song{Game: &game, GainDB: -5}
+	tests := []struct {
+		normalize Normalization
+		song      songrep.Song
+		want      float64
+	}{
+		{NormalizeOff, song, 0},
+		{NormalizeTrack, song, -5},
+		{NormalizeGame, song, -2},
+		{NormalizeGame, songrep.Song{GainDB: -5}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.normalize.String(), func(t *testing.T) {
+			assert.Equal(t, tt.want, Player{Normalize: tt.normalize}.gainDB(tt.song))
+		})
+	}
+}
+
+func TestNormalization_UnmarshalText(t *testing.T) {
+	var n Normalization
+	assert.NoError(t, n.UnmarshalText([]byte("game")))
+	assert.Equal(t, NormalizeGame, n)
+	assert.Error(t, n.UnmarshalText([]byte("album")))
+}
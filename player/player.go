@@ -9,6 +9,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"vgsgo/formats"
 	"vgsgo/songrep"
 )
 
@@ -19,6 +21,33 @@ type Player struct {
 	MaxPlays       int
 	MaxPlayTimeSec int
 	ContinuousPlay bool
+	// CheckMetadata compares the metadata of the songs with the headers of
+	// their files before playing them, and prints a warning when they differ.
+	CheckMetadata bool
+	// FadeInSec and FadeOutSec are the durations of the fade-in at the start
+	// of the song and of the fade-out at the end of its last loop.
+	FadeInSec  float32
+	FadeOutSec float32
+	// CrossfadeSec is how long a song started with Start overlaps the next
+	// one, both fading over this duration.
+	CrossfadeSec float32
+	// Outro plays the tail of the songs, after their loop end, at the end of
+	// their last loop.
+	Outro bool
+	// Normalize is how the loudness of the songs is normalized, with the
+	// gains measured by the scan command.
+	Normalize Normalization
+	// Controls are the controls of the songs (pause, seek, skip and quit),
+	// sent to mplayer in slave mode. Without them, mplayer reads its own keys.
+	Controls *Controls
+}
+
+// PlayReport describes how a song has been listened to.
+type PlayReport struct {
+	ListenedSec float32
+	Loops       int
+	Skipped     bool
+	End         EndReason
 }
 
 type RatingAction struct {
@@ -27,21 +56,64 @@ type RatingAction struct {
 	Quit   bool
 }
 
-func (p Player) Play(song songrep.Song) {
+func (p Player) Play(song songrep.Song) PlayReport {
+	if p.CheckMetadata {
+		p.checkMetadata(song)
+	}
+	start := time.Now()
+	end := p.exec(p.getArgs(song))
+	report := p.makeReport(song, float32(time.Since(start).Seconds()))
+	report.End = end
+	// quitting the program is not a skip of the song
+	switch end {
+	case EndSkipped:
+		report.Skipped = true
+	case EndQuit:
+		report.Skipped = false
+	}
+	return report
+}
+
+func (p Player) getArgs(song songrep.Song) []string {
 	var args []string
-	if p.MaxPlayTimeSec != 0 {
+	if plays, _, ok := p.outroPlays(song); ok {
+		// the song ends with its tail, it doesn't fade out
+		p.MaxPlays, p.MaxPlayTimeSec, p.FadeOutSec = plays, 0, 0
+		args = p.getArgsWithOutro(song)
+	} else if p.MaxPlayTimeSec != 0 {
 		args = p.getArgsWithMaxPlayTime(song)
 	} else {
 		args = p.getArgsWithMaxPlays(song)
 	}
-	p.exec(args)
+	gainDB := p.gainDB(song)
+	if p.FadeInSec != 0 || p.FadeOutSec != 0 || gainDB != 0 {
+		args = p.withVolume(song, args, gainDB)
+	}
+	return args
 }
 
-func (p Player) PlayIndefinitely(song songrep.Song) {
+// checkMetadata warns about the differences between the metadata of a song
+// and the header of its file. Files of unknown formats are not checked.
+func (p Player) checkMetadata(song songrep.Song) {
+	info, err := formats.ReadFile(song.AbsPath)
+	if err != nil {
+		return
+	}
+	for _, mismatch := range info.Mismatches(song.DurationSec, song.LoopStartMicro, song.LoopEndMicro) {
+		_, _ = fmt.Fprintf(p.Output, "warning: %s: %s\n", song.Path, mismatch)
+	}
+}
+
+// PlayIndefinitely loops the song until the user stops it, and tells how it
+// has been stopped.
+func (p Player) PlayIndefinitely(song songrep.Song) EndReason {
 	player := p
 	player.MaxPlays = 0
 	args := player.getArgsWithMaxPlays(song)
-	p.exec(args)
+	if gainDB := p.gainDB(song); p.FadeInSec != 0 || gainDB != 0 {
+		args = player.withVolume(song, args, gainDB)
+	}
+	return p.exec(args)
 }
 
 func (p Player) Rate() RatingAction {
@@ -176,7 +248,103 @@ func (p Player) getArgsWithMaxPlayTime(song songrep.Song) []string {
 	return args
 }
 
-func (p Player) exec(args []string) {
+// minOutroSec is the shortest tail played with Outro: a shorter tail is
+// usually the rounding of the loop end, or silence.
+const minOutroSec = 0.5
+
+// outroPlays is the number of plays of a song before its tail with Outro,
+// and the duration of the tail. With MaxPlayTimeSec, the song is played as
+// many times as the tail still fits in. It returns false when the song has
+// no tail, loops indefinitely, or is too long to play its tail in time.
+func (p Player) outroPlays(song songrep.Song) (int, float32, bool) {
+	if !p.Outro || song.LoopEndMicro == 0 {
+		return 0, 0, false
+	}
+	tail := song.DurationSec - float32(song.LoopEndMicro)/1000000.0
+	if tail < minOutroSec {
+		return 0, 0, false
+	}
+	if p.MaxPlayTimeSec == 0 {
+		return p.MaxPlays, tail, p.MaxPlays != 0
+	}
+
+	firstPlay, loop := loopDurationsSec(song)
+	remaining := float32(p.MaxPlayTimeSec) - tail - firstPlay
+	if remaining < 0 || loop <= 0 {
+		return 0, 0, false
+	}
+	return 1 + int(remaining/loop), tail, true
+}
+
+// getArgsWithOutro plays the song MaxPlays times, then from its loop end to
+// the end of the file.
+func (p Player) getArgsWithOutro(song songrep.Song) []string {
+	args := p.getArgsWithMaxPlays(song)
+	return append(args, song.AbsPath, "-ss", fmt.Sprintf("%f", float32(song.LoopEndMicro)/1000000.0))
+}
+
+// skipToleranceSec is how much shorter than expected a play can be without
+// being considered as skipped.
+const skipToleranceSec = 2.0
+
+func (p Player) makeReport(song songrep.Song, listenedSec float32) PlayReport {
+	return PlayReport{
+		ListenedSec: listenedSec,
+		Loops:       CountLoops(song, listenedSec),
+		Skipped:     listenedSec+skipToleranceSec < p.expectedDurationSec(song),
+	}
+}
+
+// expectedDurationSec is how long a song plays if the user doesn't stop it.
+// When it loops indefinitely, this is the duration of the first play.
+func (p Player) expectedDurationSec(song songrep.Song) float32 {
+	firstPlay, loop := loopDurationsSec(song)
+	if plays, tail, ok := p.outroPlays(song); ok {
+		return firstPlay + float32(plays-1)*loop + tail
+	}
+	if p.MaxPlayTimeSec != 0 {
+		return float32(p.MaxPlayTimeSec)
+	}
+	if p.MaxPlays <= 1 {
+		return firstPlay
+	}
+	return firstPlay + float32(p.MaxPlays-1)*loop
+}
+
+// loopDurationsSec returns the duration of the first play of a song (from
+// the start to the loop end) and the duration of the loop.
+func loopDurationsSec(song songrep.Song) (float32, float32) {
+	firstPlay := song.DurationSec
+	if song.LoopEndMicro != 0 {
+		firstPlay = float32(song.LoopEndMicro) / 1000000.0
+	}
+	return firstPlay, firstPlay - float32(song.LoopStartMicro)/1000000.0
+}
+
+// CountLoops is the number of complete plays of a song (the first one and
+// then each loop) in the given listening time.
+func CountLoops(song songrep.Song, listenedSec float32) int {
+	firstPlay, loop := loopDurationsSec(song)
+	if firstPlay <= 0 || listenedSec < firstPlay {
+		return 0
+	}
+	if loop <= 0 {
+		return 1
+	}
+	return 1 + int((listenedSec-firstPlay)/loop)
+}
+
+func (p Player) exec(args []string) EndReason {
+	if p.Controls != nil {
+		return p.execControlled(args)
+	}
+	if !wait(p.startProcess(args)).Success() {
+		return EndError
+	}
+	return EndFinished
+}
+
+func (p Player) startProcess(args []string) *os.Process {
 	proc, err := os.StartProcess(
 		p.Cmd,
 		args,
@@ -188,9 +356,13 @@ func (p Player) exec(args []string) {
 	if err != nil {
 		log.Fatalln(err)
 	}
+	return proc
+}
 
-	_, err = proc.Wait()
+func wait(proc *os.Process) *os.ProcessState {
+	state, err := proc.Wait()
 	if err != nil {
 		log.Fatalln(err)
 	}
+	return state
 }
@@ -3,6 +3,8 @@ package player
 import (
 	"bytes"
 	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"vgsgo/songrep"
@@ -86,3 +88,112 @@ func TestPlayer_getArgsWithMaxPlayTime(t *testing.T) {
 		})
 	}
 }
+
+func TestCountLoops(t *testing.T) {
+	tests := []struct {
+		name     string
+		song     songrep.Song
+		listened float32
+		want     int
+	}{
+		{"no duration", songrep.Song{}, 10, 0},
+		{"not finished", songrep.Song{DurationSec: 10}, 9, 0},
+		{"1 play", songrep.Song{DurationSec: 10}, 10, 1},
+		{"2 plays", songrep.Song{DurationSec: 10}, 25, 2},
+		{"loop end, not finished", songrep.Song{DurationSec: 10, LoopEndMicro: 8000000}, 7.9, 0},
+		{"loop end, 1 play", songrep.Song{DurationSec: 10, LoopEndMicro: 8000000}, 9, 1},
+		{"loop start and end, 3 plays", songrep.Song{DurationSec: 10, LoopStartMicro: 2000000, LoopEndMicro: 8000000}, 20, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CountLoops(tt.song, tt.listened))
+		})
+	}
+}
+
+func TestPlayer_makeReport(t *testing.T) {
+	song := songrep.Song{DurationSec: 10, LoopStartMicro: 2000000, LoopEndMicro: 8000000}
+	tests := []struct {
+		name        string
+		player      Player
+		listened    float32
+		wantLoops   int
+		wantSkipped bool
+	}{
+		{"1 play, finished", Player{MaxPlays: 1}, 8, 1, false},
+		{"1 play, skipped", Player{MaxPlays: 1}, 3, 0, true},
+		{"3 plays, finished", Player{MaxPlays: 3}, 20, 3, false},
+		{"3 plays, skipped", Player{MaxPlays: 3}, 15, 2, true},
+		{"infinite, skipped", Player{}, 3, 0, true},
+		{"infinite, listened", Player{}, 100, 16, false},
+		{"max play time, finished", Player{MaxPlayTimeSec: 30}, 30, 4, false},
+		{"max play time, skipped", Player{MaxPlayTimeSec: 30}, 10, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := PlayReport{ListenedSec: tt.listened, Loops: tt.wantLoops, Skipped: tt.wantSkipped}
+			assert.Equal(t, want, tt.player.makeReport(song, tt.listened))
+		})
+	}
+}
+
+func TestPlayer_checkMetadata(t *testing.T) {
+	// a BRSTM header: 32000 Hz, loop from 1.5s, 20s long
+	header := make([]byte, 0x70)
+	copy(header, "RSTM\xfe\xff")
+	header[0x13] = 0x40
+	copy(header[0x40:], "HEAD")
+	header[0x4f] = 0x18
+	header[0x61] = 1
+	copy(header[0x64:], []byte{0x7d, 0x00, 0, 0, 0, 0, 0xbb, 0x80, 0, 0x09, 0xc4, 0x00})
+	path := filepath.Join(t.TempDir(), "a.brstm")
+	assert.NoError(t, os.WriteFile(path, header, 0600))
+
+	tests := []struct {
+		name string
+		song songrep.Song
+		want string
+	}{
+		{"same", songrep.Song{Path: "a.brstm", AbsPath: path, DurationSec: 20, LoopStartMicro: 1500000}, ""},
+		{"different", songrep.Song{Path: "a.brstm", AbsPath: path, DurationSec: 20, LoopStartMicro: 3000000}, "warning: a.brstm: loop start is 3000000µs, the file says 1500000µs\n"},
+		{"unknown format", songrep.Song{Path: "a.mp3", AbsPath: filepath.Join(t.TempDir(), "a.mp3")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			Player{Output: buf}.checkMetadata(tt.song)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestPlayer_getArgs_outro(t *testing.T) {
+	song := songrep.Song{AbsPath: "/foo/bar.brstm", DurationSec: 12, LoopStartMicro: 1500000, LoopEndMicro: 8000000}
+	tests := []struct {
+		name   string
+		player Player
+		song   songrep.Song
+		want   []string
+	}{
+		{"one play", Player{MaxPlays: 1, Outro: true}, song, []string{"mplayer", "/foo/bar.brstm", "-endpos", "8.000000", "/foo/bar.brstm", "-ss", "8.000000"}},
+		{"2 plays, without fade-out", Player{MaxPlays: 2, Outro: true, FadeOutSec: 3}, song, []string{"mplayer", "/foo/bar.brstm", "-endpos", "8.000000", "/foo/bar.brstm", "-ss", "1.500000", "-endpos", "8.000000", "-loop", "1", "/foo/bar.brstm", "-ss", "8.000000"}},
+		{"max play time, the tail fits in", Player{MaxPlayTimeSec: 26, Outro: true}, song, []string{"mplayer", "/foo/bar.brstm", "-endpos", "8.000000", "/foo/bar.brstm", "-ss", "1.500000", "-endpos", "8.000000", "-loop", "2", "/foo/bar.brstm", "-ss", "8.000000"}},
+		{"max play time, too short for the tail", Player{MaxPlayTimeSec: 10, Outro: true}, song, []string{"mplayer", "/foo/bar.brstm", "-endpos", "10"}},
+		{"infinite loop", Player{Outro: true}, song, []string{"mplayer", "/foo/bar.brstm", "-endpos", "8.000000", "/foo/bar.brstm", "-ss", "1.500000", "-endpos", "8.000000", "-loop", "0"}},
+		{"no tail", Player{MaxPlays: 1, Outro: true}, songrep.Song{AbsPath: "/foo/bar.brstm", DurationSec: 8.2, LoopEndMicro: 8000000}, []string{"mplayer", "/foo/bar.brstm", "-endpos", "8.000000"}},
+		{"without outro", Player{MaxPlays: 1}, song, []string{"mplayer", "/foo/bar.brstm", "-endpos", "8.000000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.player.Cmd = "mplayer"
+			assert.Equal(t, tt.want, tt.player.getArgs(tt.song))
+		})
+	}
+}
+
+func TestPlayer_expectedDurationSec_outro(t *testing.T) {
+	song := songrep.Song{DurationSec: 12, LoopStartMicro: 1500000, LoopEndMicro: 8000000}
+	assert.Equal(t, float32(18.5), Player{MaxPlays: 2, Outro: true}.expectedDurationSec(song))
+	assert.Equal(t, float32(25), Player{MaxPlayTimeSec: 26, Outro: true}.expectedDurationSec(song))
+	assert.Equal(t, float32(14.5), Player{MaxPlays: 2}.expectedDurationSec(song))
+}
@@ -0,0 +1,56 @@
+package player
+
+import (
+	"time"
+	"vgsgo/songrep"
+)
+
+// sessionFadeOutSec is the fade-out of a song cut short by the end of the
+// session, when the player has no fade-out of its own.
+const sessionFadeOutSec = 5
+
+// Session is the budget of a listening session: a number of songs and a
+// listening time, counted from Start. Zero means no limit.
+type Session struct {
+	MaxSongs int
+	MaxTime  time.Duration
+	Start    time.Time
+	songs    int
+}
+
+// Over tells whether the budget is spent, so that no song must be started.
+func (s *Session) Over(now time.Time) bool {
+	if s.MaxSongs != 0 && s.songs >= s.MaxSongs {
+		return true
+	}
+	return s.MaxTime != 0 && now.Sub(s.Start) >= s.MaxTime
+}
+
+// AddSong counts a song started during the session.
+func (s *Session) AddSong() {
+	s.songs++
+}
+
+// Fit returns the player of the next song, so that the song ends with the
+// session: a song that would play longer than the remaining time is
+// stopped at the end of the session, with a fade-out.
+func (s *Session) Fit(p Player, song songrep.Song, now time.Time) Player {
+	if s.MaxTime == 0 {
+		return p
+	}
+	remainingSec := float32((s.MaxTime - now.Sub(s.Start)).Seconds())
+	infinite := p.MaxPlays == 0 && p.MaxPlayTimeSec == 0
+	if !infinite && p.expectedDurationSec(song) <= remainingSec {
+		return p
+	}
+
+	fitted := p
+	fitted.MaxPlayTimeSec = int(remainingSec)
+	if fitted.MaxPlayTimeSec < 1 {
+		fitted.MaxPlayTimeSec = 1
+	}
+	if fitted.FadeOutSec == 0 {
+		fitted.FadeOutSec = sessionFadeOutSec
+	}
+	return fitted
+}
@@ -0,0 +1,60 @@
+package player
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"vgsgo/songrep"
+)
+
+func TestSession_Over(t *testing.T) {
+	start := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		session Session
+		songs   int
+		now     time.Time
+		want    bool
+	}{
+		{"no limit", Session{Start: start}, 100, start.Add(10 * time.Hour), false},
+		{"songs left", Session{MaxSongs: 3, Start: start}, 2, start, false},
+		{"no song left", Session{MaxSongs: 3, Start: start}, 3, start, true},
+		{"time left", Session{MaxTime: 45 * time.Minute, Start: start}, 10, start.Add(44 * time.Minute), false},
+		{"no time left", Session{MaxTime: 45 * time.Minute, Start: start}, 10, start.Add(45 * time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.session
+			for i := 0; i < tt.songs; i++ {
+				s.AddSong()
+			}
+			assert.Equal(t, tt.want, s.Over(tt.now))
+		})
+	}
+}
+
+func TestSession_Fit(t *testing.T) {
+	start := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	session := Session{MaxTime: 10 * time.Minute, Start: start}
+	song := songrep.Song{DurationSec: 120, LoopStartMicro: 10000000, LoopEndMicro: 100000000}
+	tests := []struct {
+		name    string
+		session Session
+		player  Player
+		now     time.Time
+		want    Player
+	}{
+		{"no time limit", Session{Start: start}, Player{MaxPlays: 2}, start, Player{MaxPlays: 2}},
+		{"song ends before", session, Player{MaxPlays: 2}, start, Player{MaxPlays: 2}},
+		{"song ends after", session, Player{MaxPlays: 2}, start.Add(9 * time.Minute), Player{MaxPlays: 2, MaxPlayTimeSec: 60, FadeOutSec: 5}},
+		{"fade-out of the player", session, Player{MaxPlays: 2, FadeOutSec: 8}, start.Add(9 * time.Minute), Player{MaxPlays: 2, MaxPlayTimeSec: 60, FadeOutSec: 8}},
+		{"max play time", session, Player{MaxPlayTimeSec: 300}, start.Add(8 * time.Minute), Player{MaxPlayTimeSec: 120, FadeOutSec: 5}},
+		{"infinite loop", session, Player{}, start, Player{MaxPlayTimeSec: 600, FadeOutSec: 5}},
+		{"time is over", session, Player{MaxPlays: 1}, start.Add(11 * time.Minute), Player{MaxPlays: 1, MaxPlayTimeSec: 1, FadeOutSec: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.session.Fit(tt.player, song, tt.now))
+		})
+	}
+}
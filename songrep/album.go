@@ -0,0 +1,38 @@
+package songrep
+
+import (
+	"sort"
+	"strings"
+)
+
+// AlbumSongs returns the songs of the game with this title (case-insensitive)
+// in the order of the soundtrack: by disc, then by track number, then by
+// path. The songs of unknown disc or track number come after the others.
+func AlbumSongs(library []Song, gameTitle string) []Song {
+	songs := make([]Song, 0)
+	for _, song := range library {
+		if song.Game != nil && strings.EqualFold(song.Game.Title, gameTitle) {
+			songs = append(songs, song)
+		}
+	}
+
+	sort.SliceStable(songs, func(i, j int) bool {
+		a, b := songs[i], songs[j]
+		if a.Disc != b.Disc {
+			return knownBefore(a.Disc, b.Disc)
+		}
+		if a.TrackNumber != b.TrackNumber {
+			return knownBefore(a.TrackNumber, b.TrackNumber)
+		}
+		return a.Path < b.Path
+	})
+	return songs
+}
+
+// knownBefore compares two different numbers, 0 (unknown) coming last.
+func knownBefore(a, b int) bool {
+	if a == 0 || b == 0 {
+		return b == 0
+	}
+	return a < b
+}
@@ -0,0 +1,32 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAlbumSongs(t *testing.T) {
+	game1 := Game{Title: "Zelda"}
+	game2 := Game{Title: "Zelda II"}
+	library := []Song{
+		{Game: &game1, Path: "zelda/03.brstm", Disc: 1, TrackNumber: 3},
+		{Game: &game2, Path: "zelda2/01.brstm", TrackNumber: 1},
+		{Game: &game1, Path: "zelda/d2-01.brstm", Disc: 2, TrackNumber: 1},
+		{Game: &game1, Path: "zelda/01.brstm", Disc: 1, TrackNumber: 1},
+		{Game: &game1, Path: "zelda/b.brstm"},
+		{Game: &game1, Path: "zelda/a.brstm"},
+		{Game: &game1, Path: "zelda/extra.brstm", Disc: 1},
+		{Path: "no-game.brstm"},
+	}
+
+	paths := func(songs []Song) []string {
+		rv := make([]string, 0, len(songs))
+		for _, s := range songs {
+			rv = append(rv, s.Path)
+		}
+		return rv
+	}
+	assert.Equal(t, []string{"zelda/01.brstm", "zelda/03.brstm", "zelda/extra.brstm", "zelda/d2-01.brstm", "zelda/a.brstm", "zelda/b.brstm"}, paths(AlbumSongs(library, "zelda")))
+	assert.Equal(t, []string{"zelda2/01.brstm"}, paths(AlbumSongs(library, "Zelda II")))
+	assert.Empty(t, AlbumSongs(library, "Mario"))
+}
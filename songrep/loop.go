@@ -0,0 +1,126 @@
+package songrep
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"vgsgo/ogg"
+)
+
+// HasLoopPoints is true when the song has loop points of its own, instead of
+// looping from its start to its end.
+func (s Song) HasLoopPoints() bool {
+	return s.LoopStartMicro != 0 || s.LoopEndMicro != 0
+}
+
+// loopEndMicro is the end of the loop, the end of the song when LoopEndMicro
+// is not set.
+func (s Song) loopEndMicro() int {
+	if s.LoopEndMicro != 0 {
+		return s.LoopEndMicro
+	}
+	return int(float64(s.DurationSec) * 1000000)
+}
+
+// LoopSamples converts the loop points to samples at the sample rate: the
+// start of the loop and its length.
+func (s Song) LoopSamples(sampleRate int) (start, length int) {
+	start = microToSamples(s.LoopStartMicro, sampleRate)
+	end := microToSamples(s.loopEndMicro(), sampleRate)
+	return start, end - start
+}
+
+func microToSamples(micro, sampleRate int) int {
+	return int((int64(micro)*int64(sampleRate) + 500000) / 1000000)
+}
+
+// LoopSidecar are the loop points of a song, written next to its file for
+// the players that can't read them from the metadata files.
+type LoopSidecar struct {
+	SampleRate     int `json:"sample_rate"`
+	LoopStart      int `json:"loop_start"`
+	LoopLength     int `json:"loop_length"`
+	LoopEnd        int `json:"loop_end"`
+	LoopStartMicro int `json:"loop_start_micro"`
+	LoopEndMicro   int `json:"loop_end_micro"`
+}
+
+func MakeLoopSidecar(song Song, sampleRate int) LoopSidecar {
+	start, length := song.LoopSamples(sampleRate)
+	return LoopSidecar{
+		SampleRate:     sampleRate,
+		LoopStart:      start,
+		LoopLength:     length,
+		LoopEnd:        start + length,
+		LoopStartMicro: song.LoopStartMicro,
+		LoopEndMicro:   song.loopEndMicro(),
+	}
+}
+
+// WriteText writes the loop points in samples, with the names of the
+// LOOPSTART and LOOPLENGTH Vorbis comments.
+func (l LoopSidecar) WriteText(writer io.Writer) {
+	_, err := fmt.Fprintf(writer, "LOOPSTART=%d\nLOOPLENGTH=%d\n", l.LoopStart, l.LoopLength)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func samplesToMicro(samples int64, sampleRate int) int {
+	return int((samples*1000000 + int64(sampleRate)/2) / int64(sampleRate))
+}
+
+// vorbisLoopMicro converts the LOOPSTART and LOOPLENGTH (or LOOPEND)
+// comments of a Vorbis stream, in samples, to microseconds. The loop end is
+// 0 (the end of the song) when there is only a LOOPSTART.
+func vorbisLoopMicro(info ogg.VorbisInfo) (startMicro, endMicro int, found bool) {
+	value, found := info.Comments.Get("LOOPSTART")
+	if !found || info.SampleRate <= 0 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, false
+	}
+
+	var end int64
+	if value, found := info.Comments.Get("LOOPLENGTH"); found {
+		length, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || length <= 0 {
+			return 0, 0, false
+		}
+		end = start + length
+	} else if value, found := info.Comments.Get("LOOPEND"); found {
+		end, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || end <= start {
+			return 0, 0, false
+		}
+	}
+	if end == 0 {
+		return samplesToMicro(start, info.SampleRate), 0, true
+	}
+	return samplesToMicro(start, info.SampleRate), samplesToMicro(end, info.SampleRate), true
+}
+
+// loopFromOgg is the fallback for the OGG Vorbis files without loop points
+// in the metadata files: they are read from the comments of the file, with
+// the duration if it is unknown. Files that can't be read are ignored.
+func loopFromOgg(song *Song) {
+	if !strings.EqualFold(filepath.Ext(song.AbsPath), ".ogg") {
+		return
+	}
+	info, err := ogg.ReadVorbisFile(song.AbsPath)
+	if err != nil || info.SampleRate <= 0 {
+		return
+	}
+	if song.DurationSec == 0 && info.Samples > 0 {
+		song.DurationSec = float32(info.Samples) / float32(info.SampleRate)
+	}
+	if start, end, found := vorbisLoopMicro(info); found {
+		song.LoopStartMicro = start
+		song.LoopEndMicro = end
+	}
+}
@@ -0,0 +1,143 @@
+package songrep
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"vgsgo/ogg"
+)
+
+func TestSong_LoopSamples(t *testing.T) {
+	tests := []struct {
+		name       string
+		song       Song
+		sampleRate int
+		wantStart  int
+		wantLength int
+	}{
+		{"loop", Song{LoopStartMicro: 1500000, LoopEndMicro: 61500000}, 32000, 48000, 1920000},
+		{"rounded", Song{LoopStartMicro: 1000011, LoopEndMicro: 2000000}, 44100, 44100, 44100},
+		{"no loop end", Song{LoopStartMicro: 1000000, DurationSec: 10}, 48000, 48000, 432000},
+		{"no loop start", Song{LoopEndMicro: 500000}, 48000, 0, 24000},
+		{"long song", Song{LoopStartMicro: 3000000000, LoopEndMicro: 3600000000}, 48000, 144000000, 28800000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, length := tt.song.LoopSamples(tt.sampleRate)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantLength, length)
+		})
+	}
+}
+
+func TestSong_HasLoopPoints(t *testing.T) {
+	assert.False(t, Song{DurationSec: 10}.HasLoopPoints())
+	assert.True(t, Song{LoopStartMicro: 1}.HasLoopPoints())
+	assert.True(t, Song{LoopEndMicro: 1}.HasLoopPoints())
+}
+
+func TestLoopSidecar(t *testing.T) {
+	sidecar := MakeLoopSidecar(Song{LoopStartMicro: 1500000, DurationSec: 3}, 32000)
+
+	buf := bytes.NewBuffer([]byte{})
+	sidecar.WriteText(buf)
+	assert.Equal(t, "LOOPSTART=48000\nLOOPLENGTH=48000\n", buf.String())
+
+	content, err := json.Marshal(sidecar)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"sample_rate":32000,"loop_start":48000,"loop_length":48000,"loop_end":96000,"loop_start_micro":1500000,"loop_end_micro":3000000}`, string(content))
+}
+
+// writeOggFixture writes a tiny Ogg Vorbis file, with fake setup header and
+// audio, of the given length in samples.
+func writeOggFixture(t *testing.T, path string, sampleRate int, samples int64, comments ...string) {
+	identification := make([]byte, 30)
+	identification[0] = 1
+	copy(identification[1:], "vorbis")
+	identification[11] = 2
+	binary.LittleEndian.PutUint32(identification[12:], uint32(sampleRate))
+
+	comment := bytes.NewBuffer([]byte("\x03vorbis"))
+	_ = binary.Write(comment, binary.LittleEndian, uint32(4))
+	comment.WriteString("test")
+	_ = binary.Write(comment, binary.LittleEndian, uint32(len(comments)))
+	for _, c := range comments {
+		_ = binary.Write(comment, binary.LittleEndian, uint32(len(c)))
+		comment.WriteString(c)
+	}
+	comment.WriteByte(1)
+
+	pages := ogg.Paginate([][]byte{identification}, 1, 0, 0)
+	pages[0].HeaderType = 0x02
+	pages = append(pages, ogg.Paginate([][]byte{comment.Bytes(), []byte("\x05vorbis setup")}, 1, 1, 0)...)
+	pages = append(pages, ogg.Paginate([][]byte{[]byte("audio")}, 1, uint32(len(pages)), samples)...)
+
+	data := make([]byte, 0)
+	for _, page := range pages {
+		data = append(data, page.Bytes()...)
+	}
+	assert.NoError(t, os.WriteFile(path, data, 0600))
+}
+
+func Test_loopFromOgg(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		comments []string
+		want     Song
+	}{
+		{"start and length", []string{"LOOPSTART=48000", "LOOPLENGTH=96000"}, Song{LoopStartMicro: 1000000, LoopEndMicro: 3000000, DurationSec: 5}},
+		{"start and end", []string{"loopstart=24000", "LoopEnd=72000"}, Song{LoopStartMicro: 500000, LoopEndMicro: 1500000, DurationSec: 5}},
+		{"start only", []string{"LOOPSTART=1"}, Song{LoopStartMicro: 21, DurationSec: 5}},
+		{"no loop", []string{"TITLE=a"}, Song{DurationSec: 5}},
+		{"invalid start", []string{"LOOPSTART=abc", "LOOPLENGTH=1"}, Song{DurationSec: 5}},
+		{"invalid length", []string{"LOOPSTART=1", "LOOPLENGTH=0"}, Song{DurationSec: 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".ogg")
+			writeOggFixture(t, path, 48000, 240000, tt.comments...)
+			song := Song{AbsPath: path}
+			loopFromOgg(&song)
+			tt.want.AbsPath = path
+			assert.Equal(t, tt.want, song)
+		})
+	}
+}
+
+func Test_loopFromOgg_ignored(t *testing.T) {
+	dir := t.TempDir()
+	notOgg := filepath.Join(dir, "a.brstm")
+	writeOggFixture(t, notOgg, 48000, 48000, "LOOPSTART=1")
+	invalid := filepath.Join(dir, "b.ogg")
+	assert.NoError(t, os.WriteFile(invalid, []byte("not an ogg file"), 0600))
+	known := filepath.Join(dir, "c.OGG")
+	writeOggFixture(t, known, 48000, 48000)
+
+	for _, song := range []Song{{AbsPath: notOgg}, {AbsPath: invalid}, {AbsPath: filepath.Join(dir, "missing.ogg")}, {AbsPath: known, DurationSec: 2}} {
+		want := song
+		loopFromOgg(&song)
+		assert.Equal(t, want, song)
+	}
+}
+
+func TestSongsFromFiles_oggFallback(t *testing.T) {
+	dir := t.TempDir()
+	writeOggFixture(t, filepath.Join(dir, "tagged.ogg"), 32000, 320000, "LOOPSTART=32000", "LOOPLENGTH=160000")
+	writeOggFixture(t, filepath.Join(dir, "both.ogg"), 32000, 320000, "LOOPSTART=32000", "LOOPLENGTH=160000")
+	metadata := `[
+		{"path": "tagged.ogg", "title": "tagged", "game_title": "g", "size": 1},
+		{"path": "both.ogg", "title": "both", "game_title": "g", "duration": 9, "loop_start": 2000000, "loop_end": 4000000, "size": 1}
+	]`
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "songs.json"), []byte(metadata), 0600))
+
+	songs := SongsFromFiles([]string{filepath.Join(dir, "songs.json")})
+	assert.Len(t, songs, 2)
+	assert.Equal(t, []interface{}{float32(10), 1000000, 6000000}, []interface{}{songs[0].DurationSec, songs[0].LoopStartMicro, songs[0].LoopEndMicro})
+	// the loop points of the metadata file take precedence
+	assert.Equal(t, []interface{}{float32(9), 2000000, 4000000}, []interface{}{songs[1].DurationSec, songs[1].LoopStartMicro, songs[1].LoopEndMicro})
+}
@@ -0,0 +1,110 @@
+package songrep
+
+import (
+	"fmt"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// MatchMode is how a filter string (like Filters.TitleContains) is matched
+// against a title.
+type MatchMode int
+
+const (
+	// MatchInsensitive is a case-insensitive substring match.
+	MatchInsensitive MatchMode = iota
+	// MatchNormalized is a substring match after Unicode compatibility
+	// normalization, case folding and removal of the diacritics, so that
+	// "pokemon" matches "Pokémon" and "final" matches "Ｆｉｎａｌ".
+	MatchNormalized
+	// MatchRegexp is a regular expression (RE2 syntax), case-sensitive
+	// unless it starts with (?i).
+	MatchRegexp
+	// MatchExact is a case-sensitive substring match.
+	MatchExact
+)
+
+var matchModeNames = map[MatchMode]string{
+	MatchInsensitive: "insensitive",
+	MatchNormalized:  "normalized",
+	MatchRegexp:      "regexp",
+	MatchExact:       "exact",
+}
+
+func (m MatchMode) String() string {
+	return matchModeNames[m]
+}
+
+func (m MatchMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *MatchMode) UnmarshalText(text []byte) error {
+	for mode, name := range matchModeNames {
+		if name == string(text) {
+			*m = mode
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown match mode %q (must be insensitive, normalized, regexp or exact)", string(text))
+}
+
+// StringMatcher matches strings against a pattern in a MatchMode. An empty
+// pattern matches everything.
+type StringMatcher struct {
+	pattern string
+	mode    MatchMode
+	re      *regexp.Regexp
+}
+
+func NewStringMatcher(pattern string, mode MatchMode) (StringMatcher, error) {
+	m := StringMatcher{pattern: pattern, mode: mode}
+	switch mode {
+	case MatchInsensitive:
+		m.pattern = strings.ToLower(pattern)
+	case MatchNormalized:
+		m.pattern = NormalizeString(pattern)
+	case MatchRegexp:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return StringMatcher{}, err
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+func (m StringMatcher) Match(s string) bool {
+	if m.pattern == "" {
+		return true
+	}
+	switch m.mode {
+	case MatchInsensitive:
+		return strings.Contains(strings.ToLower(s), m.pattern)
+	case MatchNormalized:
+		return strings.Contains(NormalizeString(s), m.pattern)
+	case MatchRegexp:
+		return m.re.MatchString(s)
+	default:
+		return strings.Contains(s, m.pattern)
+	}
+}
+
+// combiningDiacritics are the accents of the Latin letters. The other marks,
+// like the dakuten of the kana (バ is not ハ), are kept.
+var combiningDiacritics = &unicode.RangeTable{R16: []unicode.Range16{{Lo: 0x0300, Hi: 0x036f, Stride: 1}}}
+
+// NormalizeString folds the case and the compatibility characters (like
+// full-width letters) and removes the diacritics.
+func NormalizeString(s string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(combiningDiacritics)), norm.NFC)
+	normalized, _, err := transform.String(t, s)
+	if err != nil {
+		return strings.ToLower(s)
+	}
+	return strings.ToLower(normalized)
+}
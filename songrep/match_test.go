@@ -0,0 +1,63 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStringMatcher_Match(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		mode    MatchMode
+		title   string
+		want    bool
+	}{
+		{"empty pattern", "", MatchExact, "The Legend of Zelda", true},
+		{"insensitive", "zelda", MatchInsensitive, "The Legend of Zelda", true},
+		{"insensitive, upper pattern", "ZELDA", MatchInsensitive, "The Legend of Zelda", true},
+		{"insensitive, accent", "pokemon", MatchInsensitive, "Pokémon Red", false},
+		{"insensitive, unicode case", "ÉCHO", MatchInsensitive, "Un écho", true},
+		{"exact", "zelda", MatchExact, "The Legend of Zelda", false},
+		{"exact, same case", "Zelda", MatchExact, "The Legend of Zelda", true},
+		{"normalized, accent", "pokemon", MatchNormalized, "Pokémon Red", true},
+		{"normalized, accented pattern", "Pokémon", MatchNormalized, "POKEMON RED", true},
+		{"normalized, macron", "okami", MatchNormalized, "Ōkami", true},
+		{"normalized, full-width", "final fantasy", MatchNormalized, "Ｆｉｎａｌ Ｆａｎｔａｓｙ", true},
+		{"normalized, japanese", "ゼルダの伝説", MatchNormalized, "ゼルダの伝説 時のオカリナ", true},
+		{"normalized, half-width katakana", "ゼルダ", MatchNormalized, "ｾﾞﾙﾀﾞの伝説", true},
+		{"normalized, dakuten", "ハ", MatchNormalized, "バトル", false},
+		{"normalized, handakuten", "ホ", MatchNormalized, "ポケモン", false},
+		{"normalized, half-width dakuten", "ガ", MatchNormalized, "ｶﾞﾉﾝ", true},
+		{"normalized, no match", "mario", MatchNormalized, "Pokémon Red", false},
+		{"regexp", `^The .* of`, MatchRegexp, "The Legend of Zelda", true},
+		{"regexp, case", `zelda$`, MatchRegexp, "The Legend of Zelda", false},
+		{"regexp, insensitive", `(?i)zelda$`, MatchRegexp, "The Legend of Zelda", true},
+		{"regexp, unicode", `^ゼルダ.+オカリナ$`, MatchRegexp, "ゼルダの伝説 時のオカリナ", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewStringMatcher(tt.pattern, tt.mode)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, m.Match(tt.title))
+		})
+	}
+}
+
+func TestNewStringMatcher_invalidRegexp(t *testing.T) {
+	_, err := NewStringMatcher("(zelda", MatchRegexp)
+	assert.Error(t, err)
+	assert.Error(t, Filters{GameTitleContains: "(zelda", GameTitleMatch: MatchRegexp}.Validate())
+	assert.NoError(t, Filters{GameTitleContains: "(zelda"}.Validate())
+}
+
+func TestMatchMode_UnmarshalText(t *testing.T) {
+	for _, mode := range []MatchMode{MatchInsensitive, MatchNormalized, MatchRegexp, MatchExact} {
+		text, _ := mode.MarshalText()
+		var got MatchMode
+		assert.NoError(t, got.UnmarshalText(text))
+		assert.Equal(t, mode, got)
+	}
+	var got MatchMode
+	assert.Error(t, got.UnmarshalText([]byte("fuzzy")))
+}
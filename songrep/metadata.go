@@ -0,0 +1,171 @@
+package songrep
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"sort"
+)
+
+// UpdateSongMetadata sets fields of songs in a metadata file, like "gain".
+// The updates are indexed by the path of the song, then by the name of the
+// field. The other fields, even those unknown to vgsgo, are kept in their
+// order, as well as the indentation and the permissions of the file.
+func UpdateSongMetadata(file string, updates map[string]map[string]interface{}) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	songs := make([]jsonObject, 0, 1000)
+	err = json.Unmarshal(content, &songs)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for i := range songs {
+		var path string
+		if err := json.Unmarshal(songs[i].values["path"], &path); err != nil {
+			continue
+		}
+		// new fields are added in a stable order
+		fields := make([]string, 0, len(updates[path]))
+		for field := range updates[path] {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			raw, err := json.Marshal(updates[path][field])
+			if err != nil {
+				log.Fatalln(err)
+			}
+			songs[i].set(field, raw)
+		}
+	}
+
+	newline := bytes.HasSuffix(bytes.TrimRight(content, " \t"), []byte("\n"))
+	if indent, indented := indentation(content); indented {
+		content, err = json.MarshalIndent(songs, "", indent)
+	} else {
+		content, err = json.Marshal(songs)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if newline {
+		content = append(content, '\n')
+	}
+	// the file is replaced at once, so that it is never left half written
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, content, stat.Mode().Perm()); err != nil {
+		log.Fatalln(err)
+	}
+	// WriteFile applies the umask
+	if err := os.Chmod(tmp, stat.Mode().Perm()); err != nil {
+		log.Fatalln(err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// indentation is the indentation of the second line of a JSON document, if
+// it spans several lines.
+func indentation(content []byte) (string, bool) {
+	lines := bytes.SplitN(bytes.TrimSpace(content), []byte("\n"), 3)
+	if len(lines) < 2 {
+		return "", false
+	}
+	return string(lines[1][:len(lines[1])-len(bytes.TrimLeft(lines[1], " \t"))]), true
+}
+
+// jsonObject is a JSON object that keeps the order of its fields.
+type jsonObject struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func (o *jsonObject) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, &o.values); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// the opening brace
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if !seen[key] {
+			o.keys = append(o.keys, key)
+			seen[key] = true
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	if o.values == nil {
+		return []byte("null"), nil
+	}
+	buf := bytes.NewBufferString("{")
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(o.values[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// set sets a field, added after the others if it is new.
+func (o *jsonObject) set(key string, value json.RawMessage) {
+	if _, found := o.values[key]; !found {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// setGameGains sets the gain of each game so that its songs, played one after
+// the other, have the loudness of a normalized song: the mean of the powers
+// of the songs is normalized, rather than the mean of their gains.
+func setGameGains(songs []Song) {
+	powers := make(map[*Game][]float64)
+	for _, song := range songs {
+		if song.HasGain {
+			powers[song.Game] = append(powers[song.Game], math.Pow(10, -float64(song.GainDB)/10))
+		}
+	}
+	for game, p := range powers {
+		var sum float64
+		for _, power := range p {
+			sum += power
+		}
+		game.GainDB = float32(-10 * math.Log10(sum/float64(len(p))))
+	}
+}
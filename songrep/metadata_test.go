@@ -0,0 +1,67 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpdateSongMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			"compact",
+			`[{"path":"a.brstm","title":"A","custom":[1,2]},{"title":"B","path":"b.brstm","gain":-1},null]`,
+			`[{"path":"a.brstm","title":"A","custom":[1,2]},{"title":"B","path":"b.brstm","gain":2.5,"loop_start":1000},null]`,
+		},
+		{
+			"indented",
+			"[\n\t{\n\t\t\"title\": \"B\",\n\t\t\"path\": \"b.brstm\"\n\t}\n]\n",
+			"[\n\t{\n\t\t\"title\": \"B\",\n\t\t\"path\": \"b.brstm\",\n\t\t\"gain\": 2.5,\n\t\t\"loop_start\": 1000\n\t}\n]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "songs.json")
+			assert.NoError(t, os.WriteFile(file, []byte(tt.content), 0640))
+			assert.NoError(t, os.Chmod(file, 0640))
+
+			UpdateSongMetadata(file, map[string]map[string]interface{}{
+				"b.brstm": {"gain": 2.5, "loop_start": 1000},
+				"c.brstm": {"gain": 1},
+			})
+
+			got, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(got))
+			stat, err := os.Stat(file)
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), stat.Mode().Perm())
+		})
+	}
+}
+
+func Test_setGameGains(t *testing.T) {
+	game1 := Game{Title: "same gains"}
+	game2 := Game{Title: "different gains"}
+	game3 := Game{Title: "no gain"}
+	songs := []Song{
+		{Game: &game1, GainDB: -3, HasGain: true},
+		{Game: &game1, GainDB: -3, HasGain: true},
+		{Game: &game1},
+		{Game: &game2, GainDB: -10, HasGain: true},
+		{Game: &game2, GainDB: 0, HasGain: true},
+		{Game: &game3},
+	}
+	setGameGains(songs)
+
+	assert.InDelta(t, -3, game1.GainDB, 0.001)
+	// the loudest song weighs more than the mean of the gains, and a gain of
+	// 0 dB is a measured gain
+	assert.InDelta(t, -7.40, game2.GainDB, 0.01)
+	assert.Equal(t, float32(0), game3.GainDB)
+}
@@ -0,0 +1,179 @@
+package songrep
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PlaylistEntry is an entry of a M3U or PLS playlist file. Path is absolute,
+// relative paths of the file being resolved from its directory.
+type PlaylistEntry struct {
+	Path        string
+	Title       string
+	DurationSec int // -1 when unknown
+}
+
+// entryTitle is the title of a song in a playlist file.
+func entryTitle(song Song) string {
+	if song.Game == nil || song.Game.Title == "" {
+		return song.Title
+	}
+	return song.Game.Title + " - " + song.Title
+}
+
+func WriteM3U8(writer io.Writer, songs []Song) {
+	w := bufio.NewWriter(writer)
+	_, _ = fmt.Fprintln(w, "#EXTM3U")
+	for _, song := range songs {
+		_, _ = fmt.Fprintf(w, "#EXTINF:%d,%s\n", int(math.Round(float64(song.DurationSec))), entryTitle(song))
+		_, _ = fmt.Fprintln(w, song.AbsPath)
+	}
+	if err := w.Flush(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func WritePLS(writer io.Writer, songs []Song) {
+	w := bufio.NewWriter(writer)
+	_, _ = fmt.Fprintln(w, "[playlist]")
+	for i, song := range songs {
+		_, _ = fmt.Fprintf(w, "File%d=%s\n", i+1, song.AbsPath)
+		_, _ = fmt.Fprintf(w, "Title%d=%s\n", i+1, entryTitle(song))
+		_, _ = fmt.Fprintf(w, "Length%d=%d\n", i+1, int(math.Round(float64(song.DurationSec))))
+	}
+	_, _ = fmt.Fprintf(w, "NumberOfEntries=%d\n", len(songs))
+	_, _ = fmt.Fprintln(w, "Version=2")
+	if err := w.Flush(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// ReadM3U reads a M3U or M3U8 playlist (the extended #EXTINF information is
+// optional). dir is the directory of the playlist file.
+func ReadM3U(reader io.Reader, dir string) []PlaylistEntry {
+	entries := make([]PlaylistEntry, 0)
+	info := PlaylistEntry{DurationSec: -1}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			duration, title, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			// the duration may be followed by attributes, like tvg-id="..."
+			duration, _, _ = strings.Cut(duration, " ")
+			info.Title = title
+			info.DurationSec = parseDuration(duration)
+		case strings.HasPrefix(line, "#"):
+		default:
+			info.Path = resolveEntryPath(line, dir)
+			entries = append(entries, info)
+			info = PlaylistEntry{DurationSec: -1}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalln(err)
+	}
+	return entries
+}
+
+// ReadPLS reads a PLS playlist. dir is the directory of the playlist file.
+func ReadPLS(reader io.Reader, dir string) []PlaylistEntry {
+	byNumber := make(map[int]*PlaylistEntry)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !found {
+			continue
+		}
+		var field string
+		for _, prefix := range []string{"File", "Title", "Length"} {
+			if strings.HasPrefix(key, prefix) {
+				field = prefix
+			}
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if field == "" || err != nil {
+			continue
+		}
+		if _, found := byNumber[number]; !found {
+			byNumber[number] = &PlaylistEntry{DurationSec: -1}
+		}
+		switch field {
+		case "File":
+			byNumber[number].Path = resolveEntryPath(value, dir)
+		case "Title":
+			byNumber[number].Title = value
+		case "Length":
+			byNumber[number].DurationSec = parseDuration(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatalln(err)
+	}
+
+	numbers := make([]int, 0, len(byNumber))
+	for number, entry := range byNumber {
+		if entry.Path != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	entries := make([]PlaylistEntry, len(numbers))
+	for i, number := range numbers {
+		entries[i] = *byNumber[number]
+	}
+	return entries
+}
+
+func parseDuration(s string) int {
+	duration, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || duration < 0 {
+		return -1
+	}
+	return int(math.Round(duration))
+}
+
+// resolveEntryPath makes the path of an entry absolute. file:// URLs are
+// converted to paths, other URLs are kept as they are.
+func resolveEntryPath(path, dir string) string {
+	if u, err := url.Parse(path); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		if u.Scheme != "file" {
+			return path
+		}
+		path = u.Path
+	}
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return filepath.Clean(path)
+}
+
+// SongsFromPlaylistEntries finds the songs of the entries in the library, by
+// their AbsPath. The paths of the entries not found are returned as missing.
+func SongsFromPlaylistEntries(entries []PlaylistEntry, library []Song) (songs []Song, missing []string) {
+	byAbsPath := make(map[string]Song, len(library))
+	for _, song := range library {
+		byAbsPath[filepath.Clean(song.AbsPath)] = song
+	}
+
+	songs = make([]Song, 0, len(entries))
+	missing = make([]string, 0)
+	for _, entry := range entries {
+		if song, found := byAbsPath[entry.Path]; found {
+			songs = append(songs, song)
+		} else {
+			missing = append(missing, entry.Path)
+		}
+	}
+	return songs, missing
+}
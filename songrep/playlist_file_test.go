@@ -0,0 +1,106 @@
+package songrep
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func makePlaylistFileSongs() []Song {
+	game := Game{Title: "Zelda"}
+	return []Song{
+		{Title: "Hyrule Field", Game: &game, DurationSec: 120.4, Path: "field.brstm", AbsPath: filepath.FromSlash("/music/zelda/field.brstm")},
+		{Title: "Title, Theme", Game: &game, DurationSec: 59.6, Path: "title.brstm", AbsPath: filepath.FromSlash("/music/zelda/title.brstm")},
+	}
+}
+
+func TestWriteM3U8(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	WriteM3U8(buf, makePlaylistFileSongs())
+	assert.Equal(t, "#EXTM3U\n"+
+		"#EXTINF:120,Zelda - Hyrule Field\n"+filepath.FromSlash("/music/zelda/field.brstm")+"\n"+
+		"#EXTINF:60,Zelda - Title, Theme\n"+filepath.FromSlash("/music/zelda/title.brstm")+"\n", buf.String())
+}
+
+func TestWritePLS(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{})
+	WritePLS(buf, makePlaylistFileSongs()[:1])
+	assert.Equal(t, "[playlist]\nFile1="+filepath.FromSlash("/music/zelda/field.brstm")+"\nTitle1=Zelda - Hyrule Field\nLength1=120\nNumberOfEntries=1\nVersion=2\n", buf.String())
+}
+
+func TestPlaylistFile_roundTrip(t *testing.T) {
+	songs := makePlaylistFileSongs()
+	want := []PlaylistEntry{
+		{Path: songs[0].AbsPath, Title: "Zelda - Hyrule Field", DurationSec: 120},
+		{Path: songs[1].AbsPath, Title: "Zelda - Title, Theme", DurationSec: 60},
+	}
+	tests := []struct {
+		name  string
+		write func(buf *bytes.Buffer)
+		read  func(buf *bytes.Buffer) []PlaylistEntry
+	}{
+		{"m3u8", func(buf *bytes.Buffer) { WriteM3U8(buf, songs) }, func(buf *bytes.Buffer) []PlaylistEntry { return ReadM3U(buf, "/elsewhere") }},
+		{"pls", func(buf *bytes.Buffer) { WritePLS(buf, songs) }, func(buf *bytes.Buffer) []PlaylistEntry { return ReadPLS(buf, "/elsewhere") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := bytes.NewBuffer([]byte{})
+			tt.write(buf)
+			entries := tt.read(buf)
+			assert.Equal(t, want, entries)
+
+			got, missing := SongsFromPlaylistEntries(entries, songs)
+			assert.Equal(t, songs, got)
+			assert.Empty(t, missing)
+		})
+	}
+}
+
+func TestReadM3U(t *testing.T) {
+	dir := filepath.FromSlash("/lists")
+	content := "\ufeff#EXTM3U\r\n" +
+		"#EXTINF:-1 tvg-id=\"x\",Some title\r\n" +
+		"songs/a.brstm\r\n" +
+		"\r\n" +
+		"# a comment\r\n" +
+		"../b.brstm\r\n" +
+		"file:///music/c.brstm\r\n" +
+		"#EXTINF:12.6,Radio\r\n" +
+		"http://example.com/stream\r\n"
+	assert.Equal(t, []PlaylistEntry{
+		{Path: filepath.FromSlash("/lists/songs/a.brstm"), Title: "Some title", DurationSec: -1},
+		{Path: filepath.FromSlash("/b.brstm"), DurationSec: -1},
+		{Path: filepath.FromSlash("/music/c.brstm"), DurationSec: -1},
+		{Path: "http://example.com/stream", Title: "Radio", DurationSec: 13},
+	}, ReadM3U(strings.NewReader(content), dir))
+}
+
+func TestReadPLS(t *testing.T) {
+	content := "[playlist]\n" +
+		"File2=b.brstm\n" +
+		"Title1=A\n" +
+		"File1=/music/a.brstm\n" +
+		"Length1=30\n" +
+		"Title3=no file\n" +
+		"NumberOfEntries=3\n" +
+		"Version=2\n"
+	assert.Equal(t, []PlaylistEntry{
+		{Path: filepath.FromSlash("/music/a.brstm"), Title: "A", DurationSec: 30},
+		{Path: filepath.FromSlash("/lists/b.brstm"), DurationSec: -1},
+	}, ReadPLS(strings.NewReader(content), filepath.FromSlash("/lists")))
+}
+
+func TestSongsFromPlaylistEntries(t *testing.T) {
+	songs := makePlaylistFileSongs()
+	entries := []PlaylistEntry{
+		{Path: songs[1].AbsPath},
+		{Path: filepath.FromSlash("/music/gone.brstm")},
+		{Path: songs[0].AbsPath},
+		{Path: songs[1].AbsPath},
+	}
+	got, missing := SongsFromPlaylistEntries(entries, songs)
+	assert.Equal(t, []Song{songs[1], songs[0], songs[1]}, got)
+	assert.Equal(t, []string{filepath.FromSlash("/music/gone.brstm")}, missing)
+}
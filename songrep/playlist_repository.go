@@ -0,0 +1,100 @@
+package songrep
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+// Playlist is a named, ordered list of song paths (Song.Path, like in the
+// rating file). A path can appear several times.
+type Playlist struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+}
+
+type PlaylistRepository struct {
+	Playlists []Playlist
+	File      string
+}
+
+func PlaylistsFromJSON(reader io.Reader) PlaylistRepository {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	playlists := make([]Playlist, 0)
+	err = json.Unmarshal(content, &playlists)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return PlaylistRepository{Playlists: playlists}
+}
+
+func (r *PlaylistRepository) Get(name string) (*Playlist, bool) {
+	for i, playlist := range r.Playlists {
+		if playlist.Name == name {
+			return &r.Playlists[i], true
+		}
+	}
+	return &Playlist{}, false
+}
+
+func (r *PlaylistRepository) Create(name string) (*Playlist, error) {
+	if _, found := r.Get(name); found {
+		return nil, fmt.Errorf("playlist %q already exists", name)
+	}
+	r.Playlists = append(r.Playlists, Playlist{Name: name, Paths: []string{}})
+	return &r.Playlists[len(r.Playlists)-1], nil
+}
+
+func (p *Playlist) Add(paths ...string) {
+	p.Paths = append(p.Paths, paths...)
+}
+
+// Remove removes all the occurrences of the path, and returns how many were
+// removed.
+func (p *Playlist) Remove(path string) int {
+	kept := make([]string, 0, len(p.Paths))
+	for _, existing := range p.Paths {
+		if existing != path {
+			kept = append(kept, existing)
+		}
+	}
+	removed := len(p.Paths) - len(kept)
+	p.Paths = kept
+	return removed
+}
+
+func (r *PlaylistRepository) Save() {
+	if len(r.File) == 0 {
+		return
+	}
+
+	fh, err := os.OpenFile(r.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.WriteJSON(fh)
+	_ = fh.Close()
+}
+
+func (r *PlaylistRepository) WriteJSON(writer io.Writer) {
+	playlists := r.Playlists
+	if playlists == nil {
+		playlists = []Playlist{}
+	}
+	content, err := json.Marshal(playlists)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	_, err = writer.Write(content)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
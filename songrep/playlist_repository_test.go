@@ -0,0 +1,59 @@
+package songrep
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestPlaylistRepository_JSON(t *testing.T) {
+	content := `[{"name":"boss","paths":["a","b","a"]},{"name":"empty","paths":[]}]`
+	r := PlaylistsFromJSON(strings.NewReader(content))
+	assert.Equal(t, []Playlist{{"boss", []string{"a", "b", "a"}}, {"empty", []string{}}}, r.Playlists)
+
+	buf := bytes.NewBuffer([]byte{})
+	r.WriteJSON(buf)
+	assert.Equal(t, content, buf.String())
+
+	buf.Reset()
+	(&PlaylistRepository{}).WriteJSON(buf)
+	assert.Equal(t, "[]", buf.String())
+}
+
+func TestPlaylistRepository_Create(t *testing.T) {
+	r := PlaylistRepository{}
+	p, err := r.Create("boss")
+	assert.NoError(t, err)
+	p.Add("a", "b")
+
+	_, err = r.Create("boss")
+	assert.Error(t, err)
+
+	got, found := r.Get("boss")
+	assert.True(t, found)
+	assert.Equal(t, []string{"a", "b"}, got.Paths)
+
+	_, found = r.Get("other")
+	assert.False(t, found)
+}
+
+func TestPlaylist_Remove(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		wantRemoved int
+		wantPaths   []string
+	}{
+		{"once", "b", 1, []string{"a", "c", "a"}},
+		{"all occurrences", "a", 2, []string{"b", "c"}},
+		{"not found", "d", 0, []string{"a", "b", "c", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Playlist{Name: "p", Paths: []string{"a", "b", "c", "a"}}
+			assert.Equal(t, tt.wantRemoved, p.Remove(tt.path))
+			assert.Equal(t, tt.wantPaths, p.Paths)
+		})
+	}
+}
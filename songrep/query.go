@@ -0,0 +1,509 @@
+package songrep
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a boolean expression over the songs, like:
+//
+//	game:"Zelda" AND (tag:battle OR rating>=4) AND NOT composer:Kondo
+//
+// Terms are combined with AND (which may be omitted), OR and NOT, and grouped
+// with parentheses. A term is a field, an operator and a value, quoted if it
+// contains spaces or special characters.
+//
+// Text fields (title, game, composer, platform, series) support ":" (contains),
+// "=" and "!=", and are case-insensitive. The tag field supports ":" and "="
+// (the song has the tag) and "!=". Numeric fields (year, track, disc,
+// duration, rating, game_rating, plays) support ":" and "=" (equal), "!=",
+// "<", "<=", ">" and ">=". A rating term is false for a song without rating.
+type Query struct {
+	Root QueryNode
+}
+
+// QueryContext is what a query is evaluated against: the song and its
+// rating data.
+type QueryContext struct {
+	Song          Song
+	Rating        float32
+	HasRating     bool
+	GameRating    float32
+	HasGameRating bool
+	Plays         int
+}
+
+type QueryNode interface {
+	Eval(ctx QueryContext) bool
+	String() string
+	precedence() int
+}
+
+type AndNode struct {
+	Left  QueryNode
+	Right QueryNode
+}
+
+type OrNode struct {
+	Left  QueryNode
+	Right QueryNode
+}
+
+type NotNode struct {
+	Node QueryNode
+}
+
+type TermNode struct {
+	Field string
+	Op    string
+	Value string
+}
+
+type fieldKind int
+
+const (
+	textField fieldKind = iota
+	tagField
+	numericField
+)
+
+var queryFields = map[string]fieldKind{
+	"title":       textField,
+	"game":        textField,
+	"composer":    textField,
+	"platform":    textField,
+	"series":      textField,
+	"tag":         tagField,
+	"year":        numericField,
+	"track":       numericField,
+	"disc":        numericField,
+	"duration":    numericField,
+	"rating":      numericField,
+	"game_rating": numericField,
+	"plays":       numericField,
+}
+
+var queryOps = map[fieldKind][]string{
+	textField:    {":", "=", "!="},
+	tagField:     {":", "=", "!="},
+	numericField: {":", "=", "!=", "<", "<=", ">", ">="},
+}
+
+type QuerySyntaxError struct {
+	Query   string
+	Pos     int
+	Message string
+}
+
+func (e QuerySyntaxError) Error() string {
+	return fmt.Sprintf("invalid query at position %d: %s", e.Pos, e.Message)
+}
+
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	p := queryParser{query: s, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, p.errorf("empty query")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf("unexpected %q", t.text)
+	}
+	return &Query{Root: root}, nil
+}
+
+// Match is true if the song matches the query. A nil query matches all the
+// songs.
+func (q *Query) Match(ctx QueryContext) bool {
+	if q == nil || q.Root == nil {
+		return true
+	}
+	return q.Root.Eval(ctx)
+}
+
+// String is the canonical form of the query, which is parsed back to an
+// equivalent query.
+func (q *Query) String() string {
+	if q == nil || q.Root == nil {
+		return ""
+	}
+	return q.Root.String()
+}
+
+func (q *Query) MarshalText() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+func (q *Query) UnmarshalText(text []byte) error {
+	if len(strings.TrimSpace(string(text))) == 0 {
+		q.Root = nil
+		return nil
+	}
+	parsed, err := ParseQuery(string(text))
+	if err != nil {
+		return err
+	}
+	q.Root = parsed.Root
+	return nil
+}
+
+func (n AndNode) Eval(ctx QueryContext) bool {
+	return n.Left.Eval(ctx) && n.Right.Eval(ctx)
+}
+
+func (n OrNode) Eval(ctx QueryContext) bool {
+	return n.Left.Eval(ctx) || n.Right.Eval(ctx)
+}
+
+func (n NotNode) Eval(ctx QueryContext) bool {
+	return !n.Node.Eval(ctx)
+}
+
+func (n TermNode) Eval(ctx QueryContext) bool {
+	song := ctx.Song
+	switch n.Field {
+	case "title":
+		return matchText(n.Op, n.Value, song.Title)
+	case "game":
+		return matchText(n.Op, n.Value, gameAttribute(song, func(g *Game) string { return g.Title }))
+	case "composer":
+		if n.Op == "!=" {
+			return matchText(n.Op, n.Value, song.Composer) && matchText(n.Op, n.Value, gameAttribute(song, func(g *Game) string { return g.Composer }))
+		}
+		return matchText(n.Op, n.Value, song.Composer) || matchText(n.Op, n.Value, gameAttribute(song, func(g *Game) string { return g.Composer }))
+	case "platform":
+		return matchText(n.Op, n.Value, gameAttribute(song, func(g *Game) string { return g.Platform }))
+	case "series":
+		return matchText(n.Op, n.Value, gameAttribute(song, func(g *Game) string { return g.Series }))
+	case "tag":
+		found := hasTags(song, []string{n.Value})
+		if n.Op == "!=" {
+			return !found
+		}
+		return found
+	case "year":
+		if song.Game == nil {
+			return matchNumber(n.Op, n.Value, 0)
+		}
+		return matchNumber(n.Op, n.Value, float64(song.Game.Year))
+	case "track":
+		return matchNumber(n.Op, n.Value, float64(song.TrackNumber))
+	case "disc":
+		return matchNumber(n.Op, n.Value, float64(song.Disc))
+	case "duration":
+		return matchNumber(n.Op, n.Value, float64(song.DurationSec))
+	case "rating":
+		return ctx.HasRating && matchNumber(n.Op, n.Value, float64(ctx.Rating))
+	case "game_rating":
+		return ctx.HasGameRating && matchNumber(n.Op, n.Value, float64(ctx.GameRating))
+	case "plays":
+		return matchNumber(n.Op, n.Value, float64(ctx.Plays))
+	}
+	return false
+}
+
+func gameAttribute(song Song, attribute func(g *Game) string) string {
+	if song.Game == nil {
+		return ""
+	}
+	return attribute(song.Game)
+}
+
+func matchText(op, value, text string) bool {
+	switch op {
+	case ":":
+		return strings.Contains(strings.ToLower(text), strings.ToLower(value))
+	case "=":
+		return strings.EqualFold(text, value)
+	case "!=":
+		return !strings.EqualFold(text, value)
+	}
+	return false
+}
+
+func matchNumber(op, value string, number float64) bool {
+	// the value has been checked by the parser
+	v, _ := strconv.ParseFloat(value, 64)
+	switch op {
+	case ":", "=":
+		return number == v
+	case "!=":
+		return number != v
+	case "<":
+		return number < v
+	case "<=":
+		return number <= v
+	case ">":
+		return number > v
+	case ">=":
+		return number >= v
+	}
+	return false
+}
+
+func (n AndNode) String() string {
+	return childString(n, n.Left) + " AND " + childString(n, n.Right)
+}
+
+func (n OrNode) String() string {
+	return childString(n, n.Left) + " OR " + childString(n, n.Right)
+}
+
+func (n NotNode) String() string {
+	return "NOT " + childString(n, n.Node)
+}
+
+func (n TermNode) String() string {
+	return n.Field + n.Op + quoteQueryValue(n.Value)
+}
+
+func (n AndNode) precedence() int  { return 2 }
+func (n OrNode) precedence() int   { return 1 }
+func (n NotNode) precedence() int  { return 3 }
+func (n TermNode) precedence() int { return 4 }
+
+func childString(parent, child QueryNode) string {
+	if child.precedence() < parent.precedence() {
+		return "(" + child.String() + ")"
+	}
+	return child.String()
+}
+
+func quoteQueryValue(value string) string {
+	needsQuotes := value == "" || strings.EqualFold(value, "AND") || strings.EqualFold(value, "OR") || strings.EqualFold(value, "NOT")
+	for _, r := range value {
+		if !isWordChar(r) || r == '\\' {
+			needsQuotes = true
+		}
+	}
+	if !needsQuotes {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenLParen
+	tokenRParen
+	tokenOp
+	tokenWord
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func isOpChar(r rune) bool {
+	return r == ':' || r == '=' || r == '!' || r == '<' || r == '>'
+}
+
+func isWordChar(r rune) bool {
+	return !unicode.IsSpace(r) && !isOpChar(r) && r != '(' && r != ')' && r != '"'
+}
+
+func lexQuery(s string) ([]token, error) {
+	tokens := make([]token, 0, 16)
+	runes := []rune(s)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case isOpChar(r):
+			start := i
+			for i < len(runes) && isOpChar(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenOp, string(runes[start:i]), start})
+		case r == '"':
+			start := i
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, QuerySyntaxError{s, start, "unterminated string"}
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					b.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				b.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenString, b.String(), start})
+		default:
+			start := i
+			for i < len(runes) && isWordChar(runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, string(runes[start:i]), start})
+		}
+	}
+	tokens = append(tokens, token{tokenEOF, "", len(runes)})
+	return tokens, nil
+}
+
+type queryParser struct {
+	query  string
+	tokens []token
+	pos    int
+}
+
+func (p *queryParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *queryParser) errorf(format string, a ...any) error {
+	return QuerySyntaxError{p.query, p.peek().pos, fmt.Sprintf(format, a...)}
+}
+
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *queryParser) parseOr() (QueryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for isKeyword(p.peek(), "OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = OrNode{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses terms combined with AND. The AND keyword may be omitted.
+func (p *queryParser) parseAnd() (QueryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		if t.kind == tokenEOF || t.kind == tokenRParen || isKeyword(t, "OR") {
+			return left, nil
+		}
+		if isKeyword(t, "AND") {
+			p.next()
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = AndNode{left, right}
+	}
+}
+
+func (p *queryParser) parseNot() (QueryNode, error) {
+	if isKeyword(p.peek(), "NOT") {
+		p.next()
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return NotNode{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (QueryNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenLParen:
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokenRParen {
+			return nil, p.errorf("missing closing parenthesis")
+		}
+		p.next()
+		return node, nil
+	case tokenWord:
+		return p.parseTerm()
+	case tokenEOF:
+		return nil, p.errorf("unexpected end of query")
+	default:
+		return nil, p.errorf("unexpected %q", t.text)
+	}
+}
+
+func (p *queryParser) parseTerm() (QueryNode, error) {
+	field := p.peek()
+	kind, found := queryFields[strings.ToLower(field.text)]
+	if !found {
+		return nil, p.errorf("unknown field %q", field.text)
+	}
+	p.next()
+
+	op := p.peek()
+	if op.kind != tokenOp {
+		return nil, p.errorf("expected an operator after %q", field.text)
+	}
+	if !isValidOp(kind, op.text) {
+		return nil, p.errorf("invalid operator %q for %q", op.text, field.text)
+	}
+	p.next()
+
+	value := p.peek()
+	if value.kind != tokenWord && value.kind != tokenString {
+		return nil, p.errorf("expected a value after %q", field.text+op.text)
+	}
+	if kind == numericField {
+		if _, err := strconv.ParseFloat(value.text, 64); err != nil {
+			return nil, p.errorf("%q is not a number", value.text)
+		}
+	}
+	p.next()
+
+	return TermNode{Field: strings.ToLower(field.text), Op: op.text, Value: value.text}, nil
+}
+
+func isValidOp(kind fieldKind, op string) bool {
+	for _, valid := range queryOps[kind] {
+		if op == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package songrep
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  QueryNode
+	}{
+		{"term", "game:zelda", TermNode{"game", ":", "zelda"}},
+		{"quoted", `title:"Hyrule Field"`, TermNode{"title", ":", "Hyrule Field"}},
+		{"escaped quote", `title:"a \"b\""`, TermNode{"title", ":", `a "b"`}},
+		{"field case", "GAME=Zelda", TermNode{"game", "=", "Zelda"}},
+		{"numeric", "rating>=4", TermNode{"rating", ">=", "4"}},
+		{"numeric with spaces", "duration < 90.5", TermNode{"duration", "<", "90.5"}},
+		{"and", "game:zelda AND tag:battle", AndNode{TermNode{"game", ":", "zelda"}, TermNode{"tag", ":", "battle"}}},
+		{"implicit and", "game:zelda tag:battle", AndNode{TermNode{"game", ":", "zelda"}, TermNode{"tag", ":", "battle"}}},
+		{"or", "tag:battle or tag:boss", OrNode{TermNode{"tag", ":", "battle"}, TermNode{"tag", ":", "boss"}}},
+		{"not", "NOT composer:Kondo", NotNode{TermNode{"composer", ":", "Kondo"}}},
+		{
+			"precedence",
+			"title:a OR title:b AND NOT title:c",
+			OrNode{TermNode{"title", ":", "a"}, AndNode{TermNode{"title", ":", "b"}, NotNode{TermNode{"title", ":", "c"}}}},
+		},
+		{
+			"parentheses",
+			`game:"Zelda" AND (tag:battle OR rating>=4) AND NOT composer:Kondo`,
+			AndNode{
+				AndNode{
+					TermNode{"game", ":", "Zelda"},
+					OrNode{TermNode{"tag", ":", "battle"}, TermNode{"rating", ">=", "4"}},
+				},
+				NotNode{TermNode{"composer", ":", "Kondo"}},
+			},
+		},
+		{"keyword as value", "title:and", TermNode{"title", ":", "and"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.Root)
+		})
+	}
+}
+
+func TestParseQuery_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		wantPos int
+	}{
+		{"empty", "  ", 2},
+		{"unknown field", "foo:bar", 0},
+		{"no operator", "title bar", 6},
+		{"no value", "title:", 6},
+		{"invalid operator", "title>=abc", 5},
+		{"not a number", "rating>=high", 8},
+		{"unterminated string", `title:"abc`, 6},
+		{"missing parenthesis", "(title:a OR title:b", 19},
+		{"extra parenthesis", "title:a)", 7},
+		{"dangling operator", "title:a AND", 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			if assert.Error(t, err) {
+				assert.Equal(t, tt.wantPos, err.(QuerySyntaxError).Pos, err.Error())
+			}
+		})
+	}
+}
+
+func TestQuery_String(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"term", "game:zelda", "game:zelda"},
+		{"quoted", `title:"Hyrule Field"`, `title:"Hyrule Field"`},
+		{"quoted keyword", `title:"and"`, `title:"and"`},
+		{"escaped", `title:"a \"b\" \\"`, `title:"a \"b\" \\"`},
+		{"implicit and", "game:zelda tag:battle", "game:zelda AND tag:battle"},
+		{"parentheses", "(title:a OR title:b) AND NOT (title:c OR title:d)", "(title:a OR title:b) AND NOT (title:c OR title:d)"},
+		{"useless parentheses", "(title:a AND title:b) OR (NOT title:c)", "title:a AND title:b OR NOT title:c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, q.String())
+
+			reparsed, err := ParseQuery(q.String())
+			assert.NoError(t, err)
+			assert.Equal(t, q, reparsed)
+		})
+	}
+}
+
+func TestQuery_JSON(t *testing.T) {
+	q, _ := ParseQuery("game:zelda tag:battle")
+	content, err := json.Marshal(struct{ Query *Query }{q})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Query":"game:zelda AND tag:battle"}`, string(content))
+
+	var got struct{ Query *Query }
+	err = json.Unmarshal(content, &got)
+	assert.NoError(t, err)
+	assert.Equal(t, q, got.Query)
+
+	err = json.Unmarshal([]byte(`{"Query":"foo:bar"}`), &got)
+	assert.Error(t, err)
+}
+
+func TestQuery_Match(t *testing.T) {
+	zelda := Game{Title: "The Legend of Zelda", Platform: "Wii", Year: 2006, Composer: "Koji Kondo", Series: "Zelda"}
+	metroid := Game{Title: "Metroid Prime", Platform: "GameCube", Year: 2002}
+	songs := map[string]QueryContext{
+		"field":  {Song: Song{Title: "Hyrule Field", Game: &zelda, Tags: []string{"overworld"}, DurationSec: 120, TrackNumber: 3}, Rating: 5, HasRating: true, Plays: 10},
+		"battle": {Song: Song{Title: "Boss Battle", Game: &zelda, Composer: "Toru Minegishi", Tags: []string{"battle", "boss"}, DurationSec: 80, Disc: 2}, Plays: 1, GameRating: 4, HasGameRating: true},
+		"prime":  {Song: Song{Title: "Title Theme", Game: &metroid, Composer: "Kenji Yamamoto", DurationSec: 60}, Rating: 3, HasRating: true},
+	}
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`game:zelda`, []string{"field", "battle"}},
+		{`game="metroid prime"`, []string{"prime"}},
+		{`game!="metroid prime"`, []string{"field", "battle"}},
+		{`title:THEME`, []string{"prime"}},
+		{`composer:kondo`, []string{"field", "battle"}},
+		{`composer:minegishi`, []string{"battle"}},
+		{`composer!="koji kondo"`, []string{"prime"}},
+		{`platform=wii`, []string{"field", "battle"}},
+		{`series:zelda`, []string{"field", "battle"}},
+		{`tag:battle`, []string{"battle"}},
+		{`tag:bat`, []string{}},
+		{`tag!=battle`, []string{"field", "prime"}},
+		{`year<2005`, []string{"prime"}},
+		{`track=3`, []string{"field"}},
+		{`disc:2`, []string{"battle"}},
+		{`duration>=80`, []string{"field", "battle"}},
+		{`rating>=4`, []string{"field"}},
+		{`rating<4`, []string{"prime"}},
+		{`game_rating>3`, []string{"battle"}},
+		{`plays>0`, []string{"field", "battle"}},
+		{`plays=0`, []string{"prime"}},
+		{`game:"Zelda" AND (tag:battle OR rating>=4) AND NOT composer:Kondo`, []string{}},
+		{`game:"Zelda" AND (tag:battle OR rating>=4) AND NOT composer:Minegishi`, []string{"field"}},
+		{`NOT game:zelda OR tag:boss`, []string{"battle", "prime"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			assert.NoError(t, err)
+			got := make([]string, 0)
+			for _, name := range []string{"field", "battle", "prime"} {
+				if q.Match(songs[name]) {
+					got = append(got, name)
+				}
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQuery_MatchNil(t *testing.T) {
+	var q *Query
+	assert.True(t, q.Match(QueryContext{}))
+	assert.Equal(t, "", q.String())
+}
@@ -0,0 +1,118 @@
+package songrep
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// RatingAggregator computes the rating of a song from its plays. Plays with
+// a rating of 0 have not been rated and must be ignored. The boolean is false
+// when no play has a rating.
+type RatingAggregator interface {
+	Aggregate(plays []Play) (float32, bool)
+}
+
+// MeanAggregator is the plain mean of the ratings.
+type MeanAggregator struct{}
+
+// DecayedMeanAggregator is a mean where the weight of each rating is halved
+// every HalfLifeSec seconds. Now is the reference timestamp; if 0, the
+// current time is used.
+type DecayedMeanAggregator struct {
+	HalfLifeSec int
+	Now         int
+}
+
+// BayesianAggregator is the mean of the ratings, pulled towards PriorMean as
+// if PriorWeight ratings of PriorMean had been added.
+type BayesianAggregator struct {
+	PriorMean   float32
+	PriorWeight float32
+}
+
+// MedianAggregator is the median of the ratings.
+type MedianAggregator struct{}
+
+func ratedPlays(plays []Play) []Play {
+	rated := make([]Play, 0, len(plays))
+	for _, p := range plays {
+		if p.Rating > 0 {
+			rated = append(rated, p)
+		}
+	}
+	return rated
+}
+
+func (a MeanAggregator) Aggregate(plays []Play) (float32, bool) {
+	total := 0
+	count := 0
+	for _, p := range ratedPlays(plays) {
+		total += p.Rating
+		count++
+	}
+	if count == 0 {
+		return .0, false
+	}
+	return float32(total) / float32(count), true
+}
+
+func (a DecayedMeanAggregator) Aggregate(plays []Play) (float32, bool) {
+	rated := ratedPlays(plays)
+	if len(rated) == 0 {
+		return .0, false
+	}
+	if a.HalfLifeSec <= 0 {
+		return MeanAggregator{}.Aggregate(rated)
+	}
+
+	now := a.Now
+	if now == 0 {
+		now = int(time.Now().Unix())
+	}
+
+	var total, weights float64
+	for _, p := range rated {
+		age := now - p.Timestamp
+		if age < 0 {
+			age = 0
+		}
+		weight := math.Pow(0.5, float64(age)/float64(a.HalfLifeSec))
+		total += weight * float64(p.Rating)
+		weights += weight
+	}
+	if weights == 0 {
+		// all the ratings are too old to weigh anything
+		return MeanAggregator{}.Aggregate(rated)
+	}
+	return float32(total / weights), true
+}
+
+func (a BayesianAggregator) Aggregate(plays []Play) (float32, bool) {
+	rated := ratedPlays(plays)
+	if len(rated) == 0 {
+		return .0, false
+	}
+	total := a.PriorMean * a.PriorWeight
+	for _, p := range rated {
+		total += float32(p.Rating)
+	}
+	return total / (a.PriorWeight + float32(len(rated))), true
+}
+
+func (a MedianAggregator) Aggregate(plays []Play) (float32, bool) {
+	rated := ratedPlays(plays)
+	if len(rated) == 0 {
+		return .0, false
+	}
+	ratings := make([]int, len(rated))
+	for i, p := range rated {
+		ratings[i] = p.Rating
+	}
+	sort.Ints(ratings)
+	middle := len(ratings) / 2
+	if len(ratings)%2 == 0 {
+		return float32(ratings[middle-1]+ratings[middle]) / 2, true
+	}
+	return float32(ratings[middle]), true
+}
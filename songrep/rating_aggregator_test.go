@@ -0,0 +1,83 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const day = 24 * 60 * 60
+
+func TestRatingAggregators(t *testing.T) {
+	now := 1700000000
+	tests := []struct {
+		name       string
+		aggregator RatingAggregator
+		plays      []Play
+		wantRating float32
+		wantFound  bool
+	}{
+		{"mean, no play", MeanAggregator{}, []Play{}, .0, false},
+		{"mean, no rating", MeanAggregator{}, []Play{{Timestamp: now, Rating: 0}}, .0, false},
+		{"mean", MeanAggregator{}, []Play{{Timestamp: now, Rating: 1}, {Timestamp: now, Rating: 0}, {Timestamp: now, Rating: 4}}, 2.5, true},
+		{"decay, no rating", DecayedMeanAggregator{HalfLifeSec: day, Now: now}, []Play{{Timestamp: now, Rating: 0}}, .0, false},
+		{"decay, same age", DecayedMeanAggregator{HalfLifeSec: day, Now: now}, []Play{{Timestamp: now - day, Rating: 2}, {Timestamp: now - day, Rating: 4}}, 3.0, true},
+		{"decay, 1 half-life", DecayedMeanAggregator{HalfLifeSec: day, Now: now}, []Play{{Timestamp: now - day, Rating: 1}, {Timestamp: now, Rating: 4}}, 3.0, true},
+		{"decay, 2 half-lives", DecayedMeanAggregator{HalfLifeSec: day, Now: now}, []Play{{Timestamp: now - 2*day, Rating: 5}, {Timestamp: now, Rating: 0}, {Timestamp: now, Rating: 5}, {Timestamp: now, Rating: 0}}, 5.0, true},
+		{"decay, future", DecayedMeanAggregator{HalfLifeSec: day, Now: now}, []Play{{Timestamp: now + day, Rating: 2}, {Timestamp: now, Rating: 4}}, 3.0, true},
+		{"decay, no half-life", DecayedMeanAggregator{Now: now}, []Play{{Timestamp: now - 100*day, Rating: 1}, {Timestamp: now, Rating: 4}}, 2.5, true},
+		{"bayes, no rating", BayesianAggregator{PriorMean: 3, PriorWeight: 2}, []Play{{Timestamp: now, Rating: 0}}, .0, false},
+		{"bayes, one 5", BayesianAggregator{PriorMean: 3, PriorWeight: 2}, []Play{{Timestamp: now, Rating: 5}}, 11.0 / 3.0, true},
+		{"bayes, many 4", BayesianAggregator{PriorMean: 3, PriorWeight: 2}, []Play{{Timestamp: now, Rating: 4}, {Timestamp: now, Rating: 4}, {Timestamp: now, Rating: 4}, {Timestamp: now, Rating: 4}, {Timestamp: now, Rating: 4}, {Timestamp: now, Rating: 4}}, 3.75, true},
+		{"bayes, no weight", BayesianAggregator{PriorMean: 3}, []Play{{Timestamp: now, Rating: 5}}, 5.0, true},
+		{"median, no rating", MedianAggregator{}, []Play{{Timestamp: now, Rating: 0}}, .0, false},
+		{"median, odd", MedianAggregator{}, []Play{{Timestamp: now, Rating: 5}, {Timestamp: now, Rating: 1}, {Timestamp: now, Rating: 0}, {Timestamp: now, Rating: 2}}, 2.0, true},
+		{"median, even", MedianAggregator{}, []Play{{Timestamp: now, Rating: 5}, {Timestamp: now, Rating: 1}, {Timestamp: now, Rating: 2}, {Timestamp: now, Rating: 4}}, 3.0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRating, gotFound := tt.aggregator.Aggregate(tt.plays)
+			assert.InDelta(t, tt.wantRating, gotRating, 0.0001)
+			assert.Equal(t, tt.wantFound, gotFound)
+		})
+	}
+}
+
+func TestInMemoryRatingRepository_RatingWithAggregator(t *testing.T) {
+	now := 1700000000
+	r := InMemoryRatingRepository{
+		PlayedSongs: []PlayedSong{
+			{"old", []Play{{Timestamp: now - 1000*day, Rating: 5}}},
+			{"new", []Play{{Timestamp: now - day, Rating: 4}, {Timestamp: now, Rating: 4}, {Timestamp: now, Rating: 4}}},
+		},
+	}
+
+	oldRating, _ := r.Rating(Song{Path: "old"})
+	newRating, _ := r.Rating(Song{Path: "new"})
+	assert.Greater(t, oldRating, newRating)
+
+	r.Aggregator = BayesianAggregator{PriorMean: 3, PriorWeight: 5}
+	oldRating, _ = r.Rating(Song{Path: "old"})
+	newRating, _ = r.Rating(Song{Path: "new"})
+	assert.Less(t, oldRating, newRating)
+}
+
+func TestInMemoryRatingRepository_GlobalMean(t *testing.T) {
+	tests := []struct {
+		name       string
+		songs      []PlayedSong
+		wantRating float32
+		wantFound  bool
+	}{
+		{"0 play", []PlayedSong{}, .0, false},
+		{"no rating", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 0}}}}, .0, false},
+		{"2 songs", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 1}, {Timestamp: 0, Rating: 0}, {Timestamp: 0, Rating: 2}}}, {"path2", []Play{{Timestamp: 0, Rating: 5}}}}, 8.0 / 3.0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := InMemoryRatingRepository{PlayedSongs: tt.songs}
+			gotRating, gotFound := r.GlobalMean()
+			assert.InDelta(t, tt.wantRating, gotRating, 0.0001)
+			assert.Equal(t, tt.wantFound, gotFound)
+		})
+	}
+}
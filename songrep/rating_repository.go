@@ -5,11 +5,31 @@ type PlayedSong struct {
 	Plays []Play `json:"plays"`
 }
 
+// Play is one listening of a song. Rating is 0 when the song has not been
+// rated. The listening fields are omitted from the JSON when empty, so older
+// rating files are read and written unchanged.
 type Play struct {
-	Timestamp int `json:"timestamp"`
-	Rating    int `json:"rating"`
+	Timestamp   int     `json:"timestamp"`
+	Rating      int     `json:"rating"`
+	ListenedSec float32 `json:"listened_sec,omitempty"`
+	Loops       int     `json:"loops,omitempty"`
+	Skipped     bool    `json:"skipped,omitempty"`
 }
 
 type RatingRepository interface {
-	AddPlay(song Song, timestamp int, rating int)
+	AddPlay(song Song, play Play)
+	// Save stores the plays added since the repository has been loaded.
+	Save()
+}
+
+// SkipsInARow is the number of the most recent plays that have been skipped.
+func SkipsInARow(plays []Play) int {
+	count := 0
+	for i := len(plays) - 1; i >= 0; i-- {
+		if !plays[i].Skipped {
+			break
+		}
+		count++
+	}
+	return count
 }
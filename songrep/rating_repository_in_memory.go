@@ -10,6 +10,7 @@ import (
 type InMemoryRatingRepository struct {
 	PlayedSongs []PlayedSong
 	File        string
+	Aggregator  RatingAggregator
 }
 
 func RatingsFromJSON(reader io.Reader) InMemoryRatingRepository {
@@ -36,11 +37,11 @@ func (r *InMemoryRatingRepository) getSongByPath(path string) (*PlayedSong, bool
 	return &PlayedSong{}, false
 }
 
-func (r *InMemoryRatingRepository) AddPlay(song Song, timestamp int, rating int) {
+func (r *InMemoryRatingRepository) AddPlay(song Song, play Play) {
 	if s, found := r.getSongByPath(song.Path); found {
-		s.Plays = append(s.Plays, Play{timestamp, rating})
+		s.Plays = append(s.Plays, play)
 	} else {
-		r.PlayedSongs = append(r.PlayedSongs, PlayedSong{Path: song.Path, Plays: []Play{{timestamp, rating}}})
+		r.PlayedSongs = append(r.PlayedSongs, PlayedSong{Path: song.Path, Plays: []Play{play}})
 	}
 
 }
@@ -60,24 +61,29 @@ func (r *InMemoryRatingRepository) Save() {
 
 func (r *InMemoryRatingRepository) Rating(song Song) (float32, bool) {
 	if s, found := r.getSongByPath(song.Path); found {
-		total := 0
-		count := 0
-		for _, p := range s.Plays {
-			if p.Rating > 0 {
-				total += p.Rating
-				count++
-			}
-		}
-		if count > 0 {
-			return float32(total) / float32(count), true
-		} else {
-			return .0, false
-		}
+		return r.aggregator().Aggregate(s.Plays)
 	} else {
 		return .0, false
 	}
 }
 
+func (r *InMemoryRatingRepository) aggregator() RatingAggregator {
+	if r.Aggregator == nil {
+		return MeanAggregator{}
+	}
+	return r.Aggregator
+}
+
+// GlobalMean is the mean of all the ratings of all the songs. It is used as
+// the prior of the BayesianAggregator.
+func (r *InMemoryRatingRepository) GlobalMean() (float32, bool) {
+	plays := make([]Play, 0, len(r.PlayedSongs))
+	for _, s := range r.PlayedSongs {
+		plays = append(plays, s.Plays...)
+	}
+	return MeanAggregator{}.Aggregate(plays)
+}
+
 func (r *InMemoryRatingRepository) WriteJSON(writer io.Writer) {
 	content, err := json.Marshal(r.PlayedSongs)
 	if err != nil {
@@ -90,6 +96,18 @@ func (r *InMemoryRatingRepository) WriteJSON(writer io.Writer) {
 	}
 }
 
+// playsByPath indexes the plays by the path of the songs.
+func (r *InMemoryRatingRepository) playsByPath() map[string][]Play {
+	plays := make(map[string][]Play, len(r.PlayedSongs))
+	for _, s := range r.PlayedSongs {
+		// the first entry of a path wins, as in getSongByPath
+		if _, found := plays[s.Path]; !found {
+			plays[s.Path] = s.Plays
+		}
+	}
+	return plays
+}
+
 func (r *InMemoryRatingRepository) Plays(song Song) []Play {
 	if s, found := r.getSongByPath(song.Path); found {
 		return s.Plays
@@ -15,11 +15,11 @@ func TestInMemoryRatingRepository_Rating(t *testing.T) {
 		wantFound  bool
 	}{
 		{"0 play", []PlayedSong{}, .0, false},
-		{"1 play", []PlayedSong{{"path", []Play{{0, 2}}}}, 2.0, true},
-		{"2 plays", []PlayedSong{{"path", []Play{{0, 1}, {0, 2}}}}, 1.5, true},
-		{"3 plays", []PlayedSong{{"path", []Play{{0, 1}, {0, 2}, {0, 3}}}}, 2.0, true},
-		{"2/3 plays", []PlayedSong{{"path", []Play{{0, 1}, {0, 1}}}, {"path2", []Play{{0, 2}}}}, 1.0, true},
-		{"3, one with no rating", []PlayedSong{{"path", []Play{{0, 1}, {0, 0}, {0, 1}}}}, 1.0, true},
+		{"1 play", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 2}}}}, 2.0, true},
+		{"2 plays", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 1}, {Timestamp: 0, Rating: 2}}}}, 1.5, true},
+		{"3 plays", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 1}, {Timestamp: 0, Rating: 2}, {Timestamp: 0, Rating: 3}}}}, 2.0, true},
+		{"2/3 plays", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 1}, {Timestamp: 0, Rating: 1}}}, {"path2", []Play{{Timestamp: 0, Rating: 2}}}}, 1.0, true},
+		{"3, one with no rating", []PlayedSong{{"path", []Play{{Timestamp: 0, Rating: 1}, {Timestamp: 0, Rating: 0}, {Timestamp: 0, Rating: 1}}}}, 1.0, true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -41,9 +41,10 @@ func TestInMemoryRatingRepository_writeJSON(t *testing.T) {
 		want  string
 	}{
 		{"0 play", []PlayedSong{}, "[]"},
-		{"1 play", []PlayedSong{{"path", []Play{{123, 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":2}]}]"},
-		{"2 plays, 1 song", []PlayedSong{{"path", []Play{{456, 1}, {789, 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":456,\"rating\":1},{\"timestamp\":789,\"rating\":2}]}]"},
-		{"2 plays, 2 song", []PlayedSong{{"path", []Play{{123, 1}}}, {"path2", []Play{{456, 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":1}]},{\"path\":\"path2\",\"plays\":[{\"timestamp\":456,\"rating\":2}]}]"},
+		{"1 play", []PlayedSong{{"path", []Play{{Timestamp: 123, Rating: 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":2}]}]"},
+		{"2 plays, 1 song", []PlayedSong{{"path", []Play{{Timestamp: 456, Rating: 1}, {Timestamp: 789, Rating: 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":456,\"rating\":1},{\"timestamp\":789,\"rating\":2}]}]"},
+		{"2 plays, 2 song", []PlayedSong{{"path", []Play{{Timestamp: 123, Rating: 1}}}, {"path2", []Play{{Timestamp: 456, Rating: 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":1}]},{\"path\":\"path2\",\"plays\":[{\"timestamp\":456,\"rating\":2}]}]"},
+		{"listening", []PlayedSong{{"path", []Play{{Timestamp: 123, ListenedSec: 3.5, Skipped: true}, {Timestamp: 456, Rating: 2, ListenedSec: 60, Loops: 2}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":0,\"listened_sec\":3.5,\"skipped\":true},{\"timestamp\":456,\"rating\":2,\"listened_sec\":60,\"loops\":2}]}]"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -67,9 +68,10 @@ func TestRatingsFromJSON(t *testing.T) {
 		data string
 	}{
 		{"0 play", InMemoryRatingRepository{PlayedSongs: []PlayedSong{}}, "[]"},
-		{"1 play", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{123, 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":2}]}]"},
-		{"2 plays, 1 song", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{456, 1}, {789, 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":456,\"rating\":1},{\"timestamp\":789,\"rating\":2}]}]"},
-		{"2 plays, 2 song", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{123, 1}}}, {"path2", []Play{{456, 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":1}]},{\"path\":\"path2\",\"plays\":[{\"timestamp\":456,\"rating\":2}]}]"},
+		{"1 play", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{Timestamp: 123, Rating: 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":2}]}]"},
+		{"2 plays, 1 song", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{Timestamp: 456, Rating: 1}, {Timestamp: 789, Rating: 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":456,\"rating\":1},{\"timestamp\":789,\"rating\":2}]}]"},
+		{"2 plays, 2 song", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{Timestamp: 123, Rating: 1}}}, {"path2", []Play{{Timestamp: 456, Rating: 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":1}]},{\"path\":\"path2\",\"plays\":[{\"timestamp\":456,\"rating\":2}]}]"},
+		{"listening", InMemoryRatingRepository{PlayedSongs: []PlayedSong{{"path", []Play{{Timestamp: 123, ListenedSec: 3.5, Skipped: true}, {Timestamp: 456, Rating: 2, ListenedSec: 60, Loops: 2}}}}}, "[{\"path\":\"path\",\"plays\":[{\"timestamp\":123,\"rating\":0,\"listened_sec\":3.5,\"skipped\":true},{\"timestamp\":456,\"rating\":2,\"listened_sec\":60,\"loops\":2}]}]"},
 	}
 	for _, tt := range tests {
 		r := strings.NewReader(tt.data)
@@ -90,17 +92,36 @@ func TestInMemoryRatingRepository_AddPlay(t *testing.T) {
 		args []args
 		want []PlayedSong
 	}{
-		{"1 call", []args{{Song{Path: "foo"}, 1, 1}}, []PlayedSong{{"foo", []Play{{1, 1}}}}},
-		{"2 calls", []args{{Song{Path: "foo"}, 1, 1}, {Song{Path: "bar"}, 2, 2}}, []PlayedSong{{"foo", []Play{{1, 1}}}, {"bar", []Play{{2, 2}}}}},
-		{"3 calls", []args{{Song{Path: "foo"}, 1, 1}, {Song{Path: "bar"}, 2, 2}, {Song{Path: "foo"}, 3, 3}}, []PlayedSong{{"foo", []Play{{1, 1}, {3, 3}}}, {"bar", []Play{{2, 2}}}}},
+		{"1 call", []args{{Song{Path: "foo"}, 1, 1}}, []PlayedSong{{"foo", []Play{{Timestamp: 1, Rating: 1}}}}},
+		{"2 calls", []args{{Song{Path: "foo"}, 1, 1}, {Song{Path: "bar"}, 2, 2}}, []PlayedSong{{"foo", []Play{{Timestamp: 1, Rating: 1}}}, {"bar", []Play{{Timestamp: 2, Rating: 2}}}}},
+		{"3 calls", []args{{Song{Path: "foo"}, 1, 1}, {Song{Path: "bar"}, 2, 2}, {Song{Path: "foo"}, 3, 3}}, []PlayedSong{{"foo", []Play{{Timestamp: 1, Rating: 1}, {Timestamp: 3, Rating: 3}}}, {"bar", []Play{{Timestamp: 2, Rating: 2}}}}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			r := &InMemoryRatingRepository{}
 			for _, args := range tt.args {
-				r.AddPlay(args.song, args.timestamp, args.rating)
+				r.AddPlay(args.song, Play{Timestamp: args.timestamp, Rating: args.rating})
 			}
 			assert.Equal(t, tt.want, r.PlayedSongs)
 		})
 	}
 }
+
+func TestSkipsInARow(t *testing.T) {
+	tests := []struct {
+		name  string
+		plays []Play
+		want  int
+	}{
+		{"0 play", []Play{}, 0},
+		{"not skipped", []Play{{Timestamp: 1}}, 0},
+		{"skipped", []Play{{Timestamp: 1, Skipped: true}}, 1},
+		{"skipped, then listened", []Play{{Timestamp: 1, Skipped: true}, {Timestamp: 2}}, 0},
+		{"listened, then skipped", []Play{{Timestamp: 1, Skipped: true}, {Timestamp: 2}, {Timestamp: 3, Skipped: true}, {Timestamp: 4, Skipped: true}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SkipsInARow(tt.plays))
+		})
+	}
+}
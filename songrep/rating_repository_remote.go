@@ -1,12 +1,13 @@
 package songrep
 
 import (
+	"bytes"
 	"crypto/md5"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"strings"
 )
 
 type RemoteRatingRepository struct {
@@ -15,11 +16,14 @@ type RemoteRatingRepository struct {
 	Password      string
 }
 
-func (r *RemoteRatingRepository) AddPlay(song Song, timestamp int, rating int) {
+func (r *RemoteRatingRepository) AddPlay(song Song, play Play) {
 	songId := computeSongId(song)
 	url := r.ServerBaseUrl + "/api/songs/" + songId + "/play/"
-	body := fmt.Sprintf("{\"timestamp\":%d,\"rating\":%d}", timestamp, rating)
-	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	body, err := json.Marshal(play)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	req, _ := http.NewRequest("POST", url, bytes.NewReader(body))
 	authHeader := "Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", r.Username, r.Password)))
 	req.Header.Set("Authorization", authHeader)
 	req.Header.Set("Content-Type", "application/json")
@@ -34,6 +38,10 @@ func (r *RemoteRatingRepository) AddPlay(song Song, timestamp int, rating int) {
 	}
 }
 
+// Save does nothing: the plays are sent to the server as they are added.
+func (r *RemoteRatingRepository) Save() {
+}
+
 func computeSongId(song Song) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(song.Path)))
 }
@@ -0,0 +1,111 @@
+package songrep
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type SearchResult struct {
+	Song  Song
+	Score float64
+}
+
+// Search ranks the songs by how well their title and game title match the
+// terms. Each term must fuzzily match the title or the game title: its
+// letters must appear in order, but not necessarily next to each other.
+// Matches are case- and accent-insensitive.
+func Search(songs []Song, terms string) []SearchResult {
+	normalizedTerms := strings.Fields(NormalizeString(terms))
+	results := make([]SearchResult, 0)
+	if len(normalizedTerms) == 0 {
+		return results
+	}
+
+	for _, song := range songs {
+		title := NormalizeString(song.Title)
+		gameTitle := NormalizeString(gameAttribute(song, func(g *Game) string { return g.Title }))
+		total := 0.0
+		matched := true
+		for _, term := range normalizedTerms {
+			titleScore, titleFound := FuzzyScore(term, title)
+			gameScore, gameFound := FuzzyScore(term, gameTitle)
+			if !titleFound && !gameFound {
+				matched = false
+				break
+			}
+			// matches in the title are worth a bit more than in the game title
+			if titleFound && titleScore*1.2 >= gameScore {
+				total += titleScore * 1.2
+			} else {
+				total += gameScore
+			}
+		}
+		if matched {
+			results = append(results, SearchResult{song, total})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Song.Title < results[j].Song.Title
+	})
+	return results
+}
+
+// FuzzyScore scores how well the pattern matches the text, if all the
+// characters of the pattern appear in order in the text. Consecutive
+// characters and characters at the start of a word score more. Both strings
+// are expected to be normalized.
+func FuzzyScore(pattern, text string) (float64, bool) {
+	p := []rune(pattern)
+	t := []rune(text)
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	// an exact substring is the best possible match
+	if index := strings.Index(text, pattern); index >= 0 {
+		score := 3.0*float64(len(p)) + 5
+		start := len([]rune(text[:index]))
+		if isWordStart(t, start) {
+			score += 5
+		}
+		if isWordEnd(t, start+len(p)) {
+			score += 3
+		}
+		return score - 0.01*float64(len(t)), true
+	}
+
+	score := 0.0
+	pi := 0
+	previous := -2
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if t[ti] != p[pi] {
+			continue
+		}
+		score += 1
+		if ti == previous+1 {
+			score += 2
+		}
+		if isWordStart(t, ti) {
+			score += 3
+		}
+		previous = ti
+		pi++
+	}
+	if pi < len(p) {
+		return 0, false
+	}
+	return score - 0.01*float64(len(t)), true
+}
+
+func isWordStart(t []rune, i int) bool {
+	return i == 0 || unicode.IsSpace(t[i-1]) || unicode.IsPunct(t[i-1])
+}
+
+func isWordEnd(t []rune, i int) bool {
+	return i == len(t) || unicode.IsSpace(t[i]) || unicode.IsPunct(t[i])
+}
@@ -0,0 +1,88 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		text      string
+		wantFound bool
+	}{
+		{"empty pattern", "", "hyrule field", true},
+		{"substring", "field", "hyrule field", true},
+		{"subsequence", "hyfld", "hyrule field", true},
+		{"wrong order", "fieldhy", "hyrule field", false},
+		{"missing letter", "hyrulex", "hyrule field", false},
+		{"unicode", "伝説", "ゼルダの伝説", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, gotFound := FuzzyScore(tt.pattern, tt.text)
+			assert.Equal(t, tt.wantFound, gotFound)
+		})
+	}
+}
+
+func TestFuzzyScore_ranking(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		better  string
+		worse   string
+	}{
+		{"substring over subsequence", "field", "hyrule field", "fire island dungeon"},
+		{"word start over middle", "field", "field theme", "cornfields"},
+		{"whole word over word start", "boss", "boss theme", "bossa nova"},
+		{"consecutive over scattered", "hyf", "hyfield", "hey you final"},
+		{"word starts over scattered", "hf", "hyrule field", "the chaff"},
+		{"shorter over longer", "field", "field", "field theme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			better, _ := FuzzyScore(tt.pattern, tt.better)
+			worse, _ := FuzzyScore(tt.pattern, tt.worse)
+			assert.Greater(t, better, worse)
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	zelda := Game{Title: "The Legend of Zelda: Twilight Princess"}
+	pokemon := Game{Title: "Pokémon Battle Revolution"}
+	songs := []Song{
+		{Title: "Hyrule Field", Game: &zelda, Path: "field"},
+		{Title: "Hidden Village", Game: &zelda, Path: "village"},
+		{Title: "Battle Theme", Game: &pokemon, Path: "battle"},
+		{Title: "Main Theme", Game: &pokemon, Path: "main"},
+	}
+	paths := func(results []SearchResult) []string {
+		rv := make([]string, len(results))
+		for i, r := range results {
+			rv[i] = r.Song.Path
+		}
+		return rv
+	}
+	tests := []struct {
+		terms string
+		want  []string
+	}{
+		{"", []string{}},
+		{"hyrule", []string{"field"}},
+		{"hyr fld", []string{"field"}},
+		{"zelda", []string{"village", "field"}},
+		{"zelda village", []string{"village"}},
+		{"POKEMON", []string{"battle", "main"}},
+		{"battle", []string{"battle", "main"}},
+		{"theme", []string{"main", "battle"}},
+		{"kirby", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.terms, func(t *testing.T) {
+			assert.Equal(t, tt.want, paths(Search(songs, tt.terms)))
+		})
+	}
+}
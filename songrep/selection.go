@@ -0,0 +1,206 @@
+package songrep
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Strategy is how the next song is chosen among the songs that match the
+// filters.
+type Strategy int
+
+const (
+	// StrategyShuffle picks a random song.
+	StrategyShuffle Strategy = iota
+	// StrategyOrdered picks the first song in the sort order.
+	StrategyOrdered
+	// StrategyWeighted picks a random song, where a song is more likely to be
+	// picked the higher its rating. Songs without rating weigh as much as
+	// the mean rating of the library.
+	StrategyWeighted
+)
+
+var strategyNames = map[Strategy]string{
+	StrategyShuffle:  "shuffle",
+	StrategyOrdered:  "ordered",
+	StrategyWeighted: "weighted",
+}
+
+func (s Strategy) String() string {
+	return strategyNames[s]
+}
+
+func (s Strategy) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Strategy) UnmarshalText(text []byte) error {
+	for strategy, name := range strategyNames {
+		if name == string(text) {
+			*s = strategy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown strategy %q (must be shuffle, ordered or weighted)", string(text))
+}
+
+// SortOrder is the order of the songs for StrategyOrdered, like "rating", or
+// "-rating" for a descending order. Its zero value keeps the order of the
+// metadata files.
+type SortOrder struct {
+	Key        string
+	Descending bool
+}
+
+// sortKeys make the comparisons of two songs, given by their index, which
+// return a negative number when a comes before b.
+var sortKeys = map[string]func(r *InMemorySongRepository) func(a, b int) int{
+	"title": compareSongs(func(a, b Song) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	}),
+	"game": compareSongs(func(a, b Song) int {
+		if c := strings.Compare(strings.ToLower(a.Game.Title), strings.ToLower(b.Game.Title)); c != 0 {
+			return c
+		}
+		if c := a.Disc - b.Disc; c != 0 {
+			return c
+		}
+		return a.TrackNumber - b.TrackNumber
+	}),
+	"year": compareSongs(func(a, b Song) int {
+		return a.Game.Year - b.Game.Year
+	}),
+	"duration": compareSongs(func(a, b Song) int {
+		return compareFloats(float64(a.DurationSec), float64(b.DurationSec))
+	}),
+	"rating": compareKeys(func(r *InMemorySongRepository, song Song) float64 {
+		rating, _ := r.RatingRepository.Rating(song)
+		return float64(rating)
+	}),
+	"plays": compareKeys(func(r *InMemorySongRepository, song Song) float64 {
+		return float64(len(r.RatingRepository.Plays(song)))
+	}),
+	"last-played": compareKeys(func(r *InMemorySongRepository, song Song) float64 {
+		return float64(lastPlayed(r.RatingRepository.Plays(song)))
+	}),
+}
+
+func compareSongs(compare func(a, b Song) int) func(r *InMemorySongRepository) func(a, b int) int {
+	return func(r *InMemorySongRepository) func(a, b int) int {
+		return func(a, b int) int {
+			return compare(r.Songs[a], r.Songs[b])
+		}
+	}
+}
+
+// compareKeys computes the key of each song once, since finding the plays of
+// a song is slow.
+func compareKeys(key func(r *InMemorySongRepository, song Song) float64) func(r *InMemorySongRepository) func(a, b int) int {
+	return func(r *InMemorySongRepository) func(a, b int) int {
+		keys := make([]float64, len(r.Songs))
+		for i, song := range r.Songs {
+			keys[i] = key(r, song)
+		}
+		return func(a, b int) int {
+			return compareFloats(keys[a], keys[b])
+		}
+	}
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (o SortOrder) String() string {
+	if o.Descending {
+		return "-" + o.Key
+	}
+	return o.Key
+}
+
+func (o SortOrder) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *SortOrder) UnmarshalText(text []byte) error {
+	key := strings.TrimPrefix(string(text), "-")
+	if _, found := sortKeys[key]; !found && key != "" {
+		return fmt.Errorf("unknown sort order %q (must be title, game, year, duration, rating, plays or last-played, prefixed with - for a descending order)", string(text))
+	}
+	*o = SortOrder{Key: key, Descending: key != "" && strings.HasPrefix(string(text), "-")}
+	return nil
+}
+
+// Selection is how the songs that match the filters are played.
+type Selection struct {
+	Strategy Strategy  `json:"strategy,omitempty"`
+	Sort     SortOrder `json:"sort,omitempty"`
+}
+
+// indices are the indices of the songs, in the order they should be
+// considered.
+func (r *InMemorySongRepository) indices(seed int64) []int {
+	switch r.Selection.Strategy {
+	case StrategyOrdered:
+		return r.sortedIndices()
+	case StrategyWeighted:
+		return r.weightedIndices(seed)
+	default:
+		return getShuffledIndices(len(r.Songs), seed)
+	}
+}
+
+func (r *InMemorySongRepository) sortedIndices() []int {
+	indices := make([]int, len(r.Songs))
+	for i := range indices {
+		indices[i] = i
+	}
+	makeCompare, found := sortKeys[r.Selection.Sort.Key]
+	if !found {
+		return indices
+	}
+	compare := makeCompare(r)
+	sort.SliceStable(indices, func(i, j int) bool {
+		c := compare(indices[i], indices[j])
+		if r.Selection.Sort.Descending {
+			return c > 0
+		}
+		return c < 0
+	})
+	return indices
+}
+
+// weightedIndices is a random order where the songs with a higher rating
+// tend to come first: each song gets a key u^(1/w), u being uniform in [0, 1)
+// and w the weight of the song, and the songs are sorted by decreasing key.
+func (r *InMemorySongRepository) weightedIndices(seed int64) []int {
+	defaultWeight, found := r.RatingRepository.GlobalMean()
+	if !found {
+		defaultWeight = 3
+	}
+	random := rand.New(rand.NewSource(seed))
+	keys := make([]float64, len(r.Songs))
+	indices := make([]int, len(r.Songs))
+	for i, song := range r.Songs {
+		weight, found := r.RatingRepository.Rating(song)
+		if !found || weight <= 0 {
+			weight = defaultWeight
+		}
+		keys[i] = math.Pow(random.Float64(), 1/float64(weight))
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return keys[indices[i]] > keys[indices[j]]
+	})
+	return indices
+}
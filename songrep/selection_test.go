@@ -0,0 +1,96 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSortOrder_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    SortOrder
+		wantErr bool
+	}{
+		{"", SortOrder{}, false},
+		{"rating", SortOrder{Key: "rating"}, false},
+		{"-last-played", SortOrder{Key: "last-played", Descending: true}, false},
+		{"-", SortOrder{}, false},
+		{"foo", SortOrder{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var got SortOrder
+			err := got.UnmarshalText([]byte(tt.text))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInMemorySongRepository_sortedIndices(t *testing.T) {
+	gameA := Game{Title: "A", Year: 2001}
+	gameB := Game{Title: "b", Year: 1999}
+	r := InMemorySongRepository{
+		Songs: []Song{
+			{Title: "z", Game: &gameB, Path: "0", DurationSec: 30, TrackNumber: 2},
+			{Title: "y", Game: &gameA, Path: "1", DurationSec: 10, TrackNumber: 3},
+			{Title: "X", Game: &gameA, Path: "2", DurationSec: 20, TrackNumber: 1},
+			{Title: "w", Game: &gameB, Path: "3", DurationSec: 40, TrackNumber: 1},
+		},
+		RatingRepository: InMemoryRatingRepository{
+			PlayedSongs: []PlayedSong{
+				{"0", []Play{{Timestamp: 100, Rating: 2}, {Timestamp: 300, Rating: 4}}},
+				{"1", []Play{{Timestamp: 200, Rating: 5}}},
+				{"3", []Play{{Timestamp: 50, Rating: 1}}},
+			},
+		},
+	}
+	tests := []struct {
+		sort string
+		want []int
+	}{
+		{"", []int{0, 1, 2, 3}},
+		{"title", []int{3, 2, 1, 0}},
+		{"game", []int{2, 1, 3, 0}},
+		{"year", []int{0, 3, 1, 2}},
+		{"duration", []int{1, 2, 0, 3}},
+		{"-duration", []int{3, 0, 2, 1}},
+		{"rating", []int{2, 3, 0, 1}},
+		{"-rating", []int{1, 0, 3, 2}},
+		{"plays", []int{2, 1, 3, 0}},
+		{"last-played", []int{2, 3, 1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			assert.NoError(t, r.Selection.Sort.UnmarshalText([]byte(tt.sort)))
+			assert.Equal(t, tt.want, r.sortedIndices())
+		})
+	}
+}
+
+func TestInMemorySongRepository_weightedIndices(t *testing.T) {
+	game := Game{Title: "game"}
+	r := InMemorySongRepository{
+		Songs: []Song{{Game: &game, Path: "low"}, {Game: &game, Path: "high"}},
+		RatingRepository: InMemoryRatingRepository{
+			PlayedSongs: []PlayedSong{
+				{"low", []Play{{Rating: 1}}},
+				{"high", []Play{{Rating: 5}}},
+			},
+		},
+	}
+	firstHigh := 0
+	for seed := int64(0); seed < 1000; seed++ {
+		indices := r.weightedIndices(seed)
+		assert.ElementsMatch(t, []int{0, 1}, indices)
+		if indices[0] == 1 {
+			firstHigh++
+		}
+	}
+	// with weights 5 and 1, the high rated song comes first 5 times out of 6
+	assert.InDelta(t, 833, firstHigh, 50)
+}
@@ -0,0 +1,84 @@
+package songrep
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+)
+
+// SmartPlaylist is a named set of filters, evaluated each time it is played.
+type SmartPlaylist struct {
+	Name    string  `json:"name"`
+	Filters Filters `json:"filters"`
+	Selection
+}
+
+type SmartPlaylistRepository struct {
+	SmartPlaylists []SmartPlaylist
+	File           string
+}
+
+func SmartPlaylistsFromJSON(reader io.Reader) SmartPlaylistRepository {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	playlists := make([]SmartPlaylist, 0)
+	err = json.Unmarshal(content, &playlists)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return SmartPlaylistRepository{SmartPlaylists: playlists}
+}
+
+func (r *SmartPlaylistRepository) Get(name string) (SmartPlaylist, bool) {
+	for _, playlist := range r.SmartPlaylists {
+		if playlist.Name == name {
+			return playlist, true
+		}
+	}
+	return SmartPlaylist{}, false
+}
+
+// Set adds the smart playlist, or replaces the one with the same name.
+func (r *SmartPlaylistRepository) Set(playlist SmartPlaylist) {
+	for i, existing := range r.SmartPlaylists {
+		if existing.Name == playlist.Name {
+			r.SmartPlaylists[i] = playlist
+			return
+		}
+	}
+	r.SmartPlaylists = append(r.SmartPlaylists, playlist)
+}
+
+func (r *SmartPlaylistRepository) Save() {
+	if len(r.File) == 0 {
+		return
+	}
+
+	fh, err := os.OpenFile(r.File, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		log.Fatal(err)
+	}
+	r.WriteJSON(fh)
+	_ = fh.Close()
+}
+
+func (r *SmartPlaylistRepository) WriteJSON(writer io.Writer) {
+	playlists := r.SmartPlaylists
+	if playlists == nil {
+		playlists = []SmartPlaylist{}
+	}
+	content, err := json.MarshalIndent(playlists, "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	_, err = writer.Write(content)
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
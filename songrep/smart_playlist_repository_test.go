@@ -0,0 +1,62 @@
+package songrep
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSmartPlaylistRepository_JSON(t *testing.T) {
+	r := SmartPlaylistRepository{}
+	r.Set(SmartPlaylist{
+		Name: "unrated zelda",
+		Filters: Filters{
+			OnlyHasNoRating:   true,
+			MinDurationSec:    90,
+			GameTitleContains: "zelda",
+			GameTitleMatch:    MatchNormalized,
+			Query:             mustParseQuery("NOT tag:jingle"),
+		},
+		Selection: Selection{Strategy: StrategyOrdered, Sort: SortOrder{Key: "rating", Descending: true}},
+	})
+	r.Set(SmartPlaylist{Name: "all"})
+
+	buf := bytes.NewBuffer([]byte{})
+	r.WriteJSON(buf)
+	assert.Equal(t, `[
+  {
+    "name": "unrated zelda",
+    "filters": {
+      "only_has_no_rating": true,
+      "min_duration": 90,
+      "game_title_contains": "zelda",
+      "query": "NOT tag:jingle",
+      "game_title_match": "normalized"
+    },
+    "strategy": "ordered",
+    "sort": "-rating"
+  },
+  {
+    "name": "all",
+    "filters": {},
+    "sort": ""
+  }
+]`, buf.String())
+
+	got := SmartPlaylistsFromJSON(buf)
+	assert.Equal(t, r.SmartPlaylists, got.SmartPlaylists)
+}
+
+func TestSmartPlaylistRepository_Set(t *testing.T) {
+	r := SmartPlaylistRepository{}
+	r.Set(SmartPlaylist{Name: "a", Filters: Filters{MinRating: 3}})
+	r.Set(SmartPlaylist{Name: "b"})
+	r.Set(SmartPlaylist{Name: "a", Filters: Filters{MinRating: 4}})
+
+	assert.Len(t, r.SmartPlaylists, 2)
+	got, found := r.Get("a")
+	assert.True(t, found)
+	assert.Equal(t, float32(4), got.Filters.MinRating)
+	_, found = r.Get("c")
+	assert.False(t, found)
+}
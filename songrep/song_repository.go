@@ -1,27 +1,87 @@
 package songrep
 
 type Game struct {
-	Title string
+	Title    string
+	Platform string
+	Year     int
+	Composer string
+	Series   string
+	// Rating is set when the game has been given a rating of its own, which
+	// takes precedence over the ratings of its songs.
+	Rating float32
+	// GainDB is the gain that normalizes the loudness of the whole game,
+	// computed from the measured gains of its songs. It is 0 when they are
+	// unknown.
+	GainDB float32
 }
 
 type Song struct {
 	Title          string
 	Game           *Game
+	Composer       string
+	TrackNumber    int
+	Disc           int
+	Tags           []string
 	DurationSec    float32
 	LoopStartMicro int
 	LoopEndMicro   int
 	Path           string
 	AbsPath        string
 	IsPlayed       bool
+	// GainDB is the gain that normalizes the loudness of the song, measured
+	// by the scan command. It is 0 when unknown.
+	GainDB float32
+	// HasGain is set when the gain has been measured, even if it is 0.
+	HasGain bool
+	// MetadataFile is the metadata file where the song is described.
+	MetadataFile string
 }
 
 type Filters struct {
-	MinRating         float32
-	OnlyHasRating     bool
-	OnlyHasNoRating   bool
-	MinDurationSec    int
-	TitleContains     string
-	GameTitleContains string
+	MinRating         float32   `json:"min_rating,omitempty"`
+	MaxRating         float32   `json:"max_rating,omitempty"`
+	OnlyHasRating     bool      `json:"only_has_rating,omitempty"`
+	OnlyHasNoRating   bool      `json:"only_has_no_rating,omitempty"`
+	MinDurationSec    int       `json:"min_duration,omitempty"`
+	MaxDurationSec    int       `json:"max_duration,omitempty"`
+	MinPlays          int       `json:"min_plays,omitempty"`
+	MaxPlays          *int      `json:"max_plays,omitempty"`        // nil for no limit
+	PlayedSince       int       `json:"played_since,omitempty"`     // unix timestamp
+	NotPlayedSince    int       `json:"not_played_since,omitempty"` // unix timestamp
+	TitleContains     string    `json:"title_contains,omitempty"`
+	GameTitleContains string    `json:"game_title_contains,omitempty"`
+	SkipLimit         int       `json:"skip_limit,omitempty"`
+	MinGameRating     float32   `json:"min_game_rating,omitempty"`
+	ExcludeGames      []string  `json:"exclude_games,omitempty"`
+	ComposerContains  string    `json:"composer_contains,omitempty"`
+	Platform          string    `json:"platform,omitempty"`
+	MinYear           int       `json:"min_year,omitempty"`
+	MaxYear           int       `json:"max_year,omitempty"`
+	Tags              []string  `json:"tags,omitempty"`
+	Query             *Query    `json:"query,omitempty"`
+	TitleMatch        MatchMode `json:"title_match,omitempty"`
+	GameTitleMatch    MatchMode `json:"game_title_match,omitempty"`
+	ComposerMatch     MatchMode `json:"composer_match,omitempty"`
+}
+
+// Validate checks that the filters can be used, like the regular expressions
+// of the MatchRegexp mode.
+func (f Filters) Validate() error {
+	_, _, _, err := f.matchers()
+	return err
+}
+
+func (f Filters) matchers() (title, gameTitle, composer StringMatcher, err error) {
+	title, err = NewStringMatcher(f.TitleContains, f.TitleMatch)
+	if err != nil {
+		return
+	}
+	gameTitle, err = NewStringMatcher(f.GameTitleContains, f.GameTitleMatch)
+	if err != nil {
+		return
+	}
+	composer, err = NewStringMatcher(f.ComposerContains, f.ComposerMatch)
+	return
 }
 
 type SongRepository interface {
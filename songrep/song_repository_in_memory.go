@@ -13,17 +13,28 @@ import (
 )
 
 type parsedSongs struct {
-	Path           string  `json:"path"`
-	Title          string  `json:"title"`
-	GameTitle      string  `json:"game_title"`
-	DurationSec    float32 `json:"duration"`
-	LoopStartMicro int     `json:"loop_start"`
-	LoopEndMicro   int     `json:"loop_end"`
-	Size           int     `json:"size"`
-	Error          bool    `json:"error"`
+	Path           string   `json:"path"`
+	Title          string   `json:"title"`
+	GameTitle      string   `json:"game_title"`
+	DurationSec    float32  `json:"duration"`
+	LoopStartMicro int      `json:"loop_start"`
+	LoopEndMicro   int      `json:"loop_end"`
+	Size           int      `json:"size"`
+	Error          bool     `json:"error"`
+	Platform       string   `json:"platform"`
+	Year           int      `json:"year"`
+	Composer       string   `json:"composer"`
+	GameComposer   string   `json:"game_composer"`
+	Series         string   `json:"series"`
+	GameRating     float32  `json:"game_rating"`
+	TrackNumber    int      `json:"track"`
+	Disc           int      `json:"disc"`
+	Tags           []string `json:"tags"`
+	GainDB         *float32 `json:"gain"`
 }
 
 type parseFileResult struct {
+	file    string
 	absPath string
 	songs   []parsedSongs
 }
@@ -31,6 +42,7 @@ type parseFileResult struct {
 type InMemorySongRepository struct {
 	Songs            []Song
 	RatingRepository InMemoryRatingRepository
+	Selection        Selection
 }
 
 func SongsFromFiles(files []string) []Song {
@@ -69,6 +81,7 @@ func parseFiles(files []string) []parseFileResult {
 			}
 		}
 		parsed = append(parsed, parseFileResult{
+			file:    filepath.Join(abs, filepath.Base(file)),
 			absPath: abs,
 			songs:   songs,
 		})
@@ -105,20 +118,49 @@ func convertImportedSongs(parsed []parseFileResult) []Song {
 				game := Game{Title: s.GameTitle}
 				games[s.GameTitle] = &game
 			}
-			songs = append(songs, makeSongFromImported(s, games[s.GameTitle], fullPath))
+			updateGameFromImported(games[s.GameTitle], s)
+			song := makeSongFromImported(s, games[s.GameTitle], fullPath)
+			song.MetadataFile = p.file
+			if !song.HasLoopPoints() {
+				loopFromOgg(&song)
+			}
+			songs = append(songs, song)
 		}
 	}
+	setGameGains(songs)
 	return songs
 }
 
-func fileExists(path string) bool {
+// updateGameFromImported sets the game metadata that are still unknown. They
+// are usually repeated on each song of the game, but may be set on only some
+// of them.
+func updateGameFromImported(game *Game, parsed parsedSongs) {
+	if game.Platform == "" {
+		game.Platform = parsed.Platform
+	}
+	if game.Year == 0 {
+		game.Year = parsed.Year
+	}
+	if game.Composer == "" {
+		game.Composer = parsed.GameComposer
+	}
+	if game.Series == "" {
+		game.Series = parsed.Series
+	}
+	if game.Rating == 0 {
+		game.Rating = parsed.GameRating
+	}
+}
+
+// fileExists tells whether a file exists. The error is set when it can't be
+// known, like when a directory can't be read.
+func fileExists(path string) (bool, error) {
 	if _, err := os.Stat(path); err == nil {
-		return true
+		return true, nil
 	} else if errors.Is(err, os.ErrNotExist) {
-		return false
+		return false, nil
 	} else {
-		log.Fatalln(err)
-		return false // because goland complains
+		return false, err
 	}
 }
 
@@ -131,20 +173,27 @@ func countParsedSongs(parsed []parseFileResult) int {
 }
 
 func makeSongFromImported(parsed parsedSongs, game *Game, absPath string) Song {
-	return Song{
+	song := Song{
 		Title:          parsed.Title,
 		Game:           game,
+		Composer:       parsed.Composer,
+		TrackNumber:    parsed.TrackNumber,
+		Disc:           parsed.Disc,
+		Tags:           parsed.Tags,
 		DurationSec:    parsed.DurationSec,
 		LoopStartMicro: parsed.LoopStartMicro,
 		LoopEndMicro:   parsed.LoopEndMicro,
 		Path:           parsed.Path,
 		AbsPath:        absPath,
 	}
+	if parsed.GainDB != nil {
+		song.GainDB, song.HasGain = *parsed.GainDB, true
+	}
+	return song
 }
 
 func (r *InMemorySongRepository) GetRandomSong(filters Filters) (Song, bool) {
-	l := len(r.Songs)
-	indices := getShuffledIndices(l, time.Now().Unix())
+	indices := r.indices(time.Now().Unix())
 	index, found := r.getFirstFilteredSong(filters, indices)
 	if found {
 		r.Songs[index].IsPlayed = true
@@ -155,6 +204,42 @@ func (r *InMemorySongRepository) GetRandomSong(filters Filters) (Song, bool) {
 }
 
 func (r *InMemorySongRepository) getFirstFilteredSong(filters Filters, indices []int) (int, bool) {
+	filtered := r.getFilteredSongs(filters, indices, 1)
+	if len(filtered) == 0 {
+		return 0, false
+	}
+	return filtered[0], true
+}
+
+// FilteredSongs are the songs that match the filters and haven't been
+// played yet, in the sort order of the Selection if any, otherwise in the
+// order of the metadata files.
+func (r *InMemorySongRepository) FilteredSongs(filters Filters) []Song {
+	indices := r.getFilteredSongs(filters, r.sortedIndices(), 0)
+	songs := make([]Song, len(indices))
+	for i, index := range indices {
+		songs[i] = r.Songs[index]
+	}
+	return songs
+}
+
+// getFilteredSongs returns the indices of the songs that match the filters,
+// at most limit of them (0 for no limit).
+func (r *InMemorySongRepository) getFilteredSongs(filters Filters, indices []int, limit int) []int {
+	filtered := make([]int, 0)
+	titleMatcher, gameTitleMatcher, composerMatcher, err := filters.matchers()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var gameRatings map[*Game]float32
+	if filters.MinGameRating > 0 || filters.Query != nil {
+		gameRatings = r.gameRatings()
+	}
+	// the plays are looked up once per call, not with a scan for each song
+	playsByPath := r.RatingRepository.playsByPath()
+	aggregator := r.RatingRepository.aggregator()
+
 	for _, index := range indices {
 		song := r.Songs[index]
 		if song.IsPlayed {
@@ -163,13 +248,21 @@ func (r *InMemorySongRepository) getFirstFilteredSong(filters Filters, indices [
 		if filters.MinDurationSec > 0 && song.DurationSec < float32(filters.MinDurationSec) {
 			continue
 		}
-		if filters.TitleContains != "" && !strings.Contains(song.Title, filters.TitleContains) {
+		if filters.MaxDurationSec > 0 && song.DurationSec > float32(filters.MaxDurationSec) {
 			continue
 		}
-		if filters.GameTitleContains != "" && !strings.Contains(song.Game.Title, filters.GameTitleContains) {
+		if !titleMatcher.Match(song.Title) {
 			continue
 		}
-		rating, found := r.RatingRepository.Rating(song)
+		if !gameTitleMatcher.Match(song.Game.Title) {
+			continue
+		}
+		plays, played := playsByPath[song.Path]
+		var rating float32
+		var found bool
+		if played {
+			rating, found = aggregator.Aggregate(plays)
+		}
 		if filters.OnlyHasRating && !found {
 			continue
 		}
@@ -179,9 +272,127 @@ func (r *InMemorySongRepository) getFirstFilteredSong(filters Filters, indices [
 		if found && rating < filters.MinRating {
 			continue
 		}
-		return index, true
+		if found && filters.MaxRating > 0 && rating > filters.MaxRating {
+			continue
+		}
+		if filters.SkipLimit > 0 && SkipsInARow(plays) >= filters.SkipLimit {
+			continue
+		}
+		if len(plays) < filters.MinPlays {
+			continue
+		}
+		if filters.MaxPlays != nil && len(plays) > *filters.MaxPlays {
+			continue
+		}
+		if filters.PlayedSince > 0 && lastPlayed(plays) < filters.PlayedSince {
+			continue
+		}
+		if filters.NotPlayedSince > 0 && lastPlayed(plays) >= filters.NotPlayedSince {
+			continue
+		}
+		if !composerMatcher.Match(song.Composer) && !composerMatcher.Match(song.Game.Composer) {
+			continue
+		}
+		if filters.Platform != "" && !strings.EqualFold(song.Game.Platform, filters.Platform) {
+			continue
+		}
+		if filters.MinYear > 0 && song.Game.Year < filters.MinYear {
+			continue
+		}
+		if filters.MaxYear > 0 && (song.Game.Year == 0 || song.Game.Year > filters.MaxYear) {
+			continue
+		}
+		if !hasTags(song, filters.Tags) {
+			continue
+		}
+		if isGameExcluded(song.Game, filters.ExcludeGames) {
+			continue
+		}
+		gameRating, hasGameRating := gameRatings[song.Game]
+		if hasGameRating && gameRating < filters.MinGameRating {
+			continue
+		}
+		if filters.Query != nil {
+			ctx := QueryContext{
+				Song:          song,
+				Rating:        rating,
+				HasRating:     found,
+				GameRating:    gameRating,
+				HasGameRating: hasGameRating,
+				Plays:         len(plays),
+			}
+			if !filters.Query.Match(ctx) {
+				continue
+			}
+		}
+		filtered = append(filtered, index)
+		if limit > 0 && len(filtered) == limit {
+			break
+		}
 	}
-	return 0, false
+	return filtered
+}
+
+// GameRating is the rating of the game if it has one of its own, otherwise
+// the mean of the ratings of its songs.
+func (r *InMemorySongRepository) GameRating(game *Game) (float32, bool) {
+	rating, found := r.gameRatings()[game]
+	return rating, found
+}
+
+// gameRatings computes the ratings of all the games at once, the plays being
+// looked up by path rather than with a scan of the ratings for each song.
+func (r *InMemorySongRepository) gameRatings() map[*Game]float32 {
+	plays := r.RatingRepository.playsByPath()
+	aggregator := r.RatingRepository.aggregator()
+	totals := make(map[*Game]float32)
+	counts := make(map[*Game]int)
+	for _, song := range r.Songs {
+		songPlays, played := plays[song.Path]
+		if !played {
+			continue
+		}
+		if rating, found := aggregator.Aggregate(songPlays); found {
+			totals[song.Game] += rating
+			counts[song.Game]++
+		}
+	}
+
+	ratings := make(map[*Game]float32, len(totals))
+	for _, song := range r.Songs {
+		if song.Game.Rating > 0 {
+			ratings[song.Game] = song.Game.Rating
+		} else if counts[song.Game] > 0 {
+			ratings[song.Game] = totals[song.Game] / float32(counts[song.Game])
+		}
+	}
+	return ratings
+}
+
+// hasTags is true if the song has all the tags.
+func hasTags(song Song, tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, songTag := range song.Tags {
+			if strings.EqualFold(songTag, tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isGameExcluded(game *Game, excluded []string) bool {
+	for _, title := range excluded {
+		if strings.EqualFold(game.Title, title) {
+			return true
+		}
+	}
+	return false
 }
 
 func getShuffledIndices(n int, seed int64) []int {
@@ -196,3 +407,14 @@ func getShuffledIndices(n int, seed int64) []int {
 	})
 	return rv
 }
+
+// lastPlayed is the timestamp of the most recent play, 0 if never played.
+func lastPlayed(plays []Play) int {
+	last := 0
+	for _, play := range plays {
+		if play.Timestamp > last {
+			last = play.Timestamp
+		}
+	}
+	return last
+}
@@ -28,19 +28,20 @@ func Test_getShuffledIndices(t *testing.T) {
 }
 
 func TestInMemorySongRepository_getFirstFilteredSong(t *testing.T) {
-	game1 := Game{Title: "foo abc"}
-	game2 := Game{Title: "foo def"}
+	game1 := Game{Title: "foo abc", Platform: "Wii", Year: 2008, Composer: "Kondo"}
+	game2 := Game{Title: "foo def", Platform: "GameCube", Year: 2002}
 	songs := []Song{
-		{Title: "bar ghi", Game: &game1, DurationSec: 10, Path: "foo"}, // "shuffled index": 2
-		{Title: "bar jkl", Game: &game1, DurationSec: 20, Path: "bar"}, // "shuffled index": 1
-		{Title: "bar mno", Game: &game2, DurationSec: 30, Path: "baz"}, // "shuffled index": 0
-		{Title: "bar pqr", Game: &game2, DurationSec: 40, Path: "biz"}, // "shuffled index": 3
+		{Title: "bar ghi", Game: &game1, DurationSec: 10, Path: "foo", Tags: []string{"battle"}},                              // "shuffled index": 2
+		{Title: "bar jkl", Game: &game1, DurationSec: 20, Path: "bar", Composer: "Someone", Tags: []string{"battle", "boss"}}, // "shuffled index": 1
+		{Title: "bar mno", Game: &game2, DurationSec: 30, Path: "baz", Tags: []string{"town"}},                                // "shuffled index": 0
+		{Title: "bar pqr", Game: &game2, DurationSec: 40, Path: "biz", Composer: "Kondo"},                                     // "shuffled index": 3
 	}
 	ratings := InMemoryRatingRepository{
 		PlayedSongs: []PlayedSong{
-			{"foo", []Play{{Rating: 5}, {Rating: 0}, {Rating: 3}}},
-			{"biz", []Play{{Rating: 2}}},
-			{"baz", []Play{{Rating: 1}}},
+			{"foo", []Play{{Timestamp: 100, Rating: 5}, {Timestamp: 200, Rating: 0}, {Timestamp: 300, Rating: 3}}},
+			{"biz", []Play{{Timestamp: 150, Rating: 2}}},
+			{"baz", []Play{{Timestamp: 400, Rating: 1}}},
+			{"bar", []Play{{Skipped: true}, {Skipped: true}}},
 		},
 	}
 	tests := []struct {
@@ -55,12 +56,20 @@ func TestInMemorySongRepository_getFirstFilteredSong(t *testing.T) {
 		{"duration >= 30", Filters{MinDurationSec: 30}, 2, true},
 		{"duration >= 40", Filters{MinDurationSec: 40}, 3, true},
 		{"duration >= 50", Filters{MinDurationSec: 50}, 0, false},
+		{"duration <= 20", Filters{MaxDurationSec: 20}, 1, true},
+		{"duration <= 5", Filters{MaxDurationSec: 5}, 0, false},
+		{"20 <= duration <= 20", Filters{MinDurationSec: 20, MaxDurationSec: 20}, 1, true},
 		{"song title 'bar'", Filters{TitleContains: "bar"}, 2, true},
 		{"song title 'jkl'", Filters{TitleContains: "jkl"}, 1, true},
 		{"song title 'not found'", Filters{TitleContains: "not found"}, 0, false},
+		{"song title 'JKL'", Filters{TitleContains: "JKL"}, 1, true},
+		{"song title 'JKL', exact", Filters{TitleContains: "JKL", TitleMatch: MatchExact}, 0, false},
+		{"song title 'j.l', regexp", Filters{TitleContains: "j.l$", TitleMatch: MatchRegexp}, 1, true},
 		{"game title 'foo'", Filters{GameTitleContains: "foo"}, 2, true},
 		{"game title 'abc'", Filters{GameTitleContains: "abc"}, 1, true},
 		{"game title 'not found'", Filters{GameTitleContains: "not found"}, 0, false},
+		{"game title 'ÂBC', normalized", Filters{GameTitleContains: "ÂBC", GameTitleMatch: MatchNormalized}, 1, true},
+		{"game title 'ÂBC'", Filters{GameTitleContains: "ÂBC"}, 0, false},
 		{"3 filters", Filters{MinDurationSec: 20, TitleContains: "bar", GameTitleContains: "abc"}, 1, true},
 		{"3 filters, not found", Filters{MinDurationSec: 30, TitleContains: "bar", GameTitleContains: "abc"}, 0, false},
 		// ratings
@@ -68,6 +77,51 @@ func TestInMemorySongRepository_getFirstFilteredSong(t *testing.T) {
 		{"rating >= 4 or no rating", Filters{MinRating: 4}, 1, true},
 		{"no rating", Filters{OnlyHasNoRating: true}, 1, true},
 		{"rating", Filters{OnlyHasRating: true}, 2, true},
+		{"rating <= 1", Filters{MaxRating: 1}, 2, true},
+		{"rating <= 0.5 or no rating", Filters{MaxRating: 0.5}, 1, true},
+		{"rating <= 0.5", Filters{MaxRating: 0.5, OnlyHasRating: true}, 0, false},
+		{"2 <= rating <= 4", Filters{MinRating: 2, MaxRating: 4, OnlyHasRating: true}, 0, true},
+		// plays
+		{"plays >= 2", Filters{MinPlays: 2}, 1, true},
+		{"plays >= 3", Filters{MinPlays: 3}, 0, true},
+		{"plays >= 4", Filters{MinPlays: 4}, 0, false},
+		{"plays <= 1", Filters{MaxPlays: intPointer(1)}, 2, true},
+		{"plays <= 1, game abc", Filters{MaxPlays: intPointer(1), GameTitleContains: "abc"}, 0, false},
+		{"never played", Filters{MaxPlays: intPointer(0)}, 0, false},
+		{"played since 350", Filters{PlayedSince: 350}, 2, true},
+		{"played since 250, game abc", Filters{PlayedSince: 250, GameTitleContains: "abc"}, 0, true},
+		{"played since 500", Filters{PlayedSince: 500}, 0, false},
+		{"not played since 350", Filters{NotPlayedSince: 350}, 1, true},
+		{"not played since 200, rating", Filters{NotPlayedSince: 200, OnlyHasRating: true}, 3, true},
+		{"not played since 100, rating", Filters{NotPlayedSince: 100, OnlyHasRating: true}, 0, false},
+		// skips
+		{"skip limit 2", Filters{SkipLimit: 2, GameTitleContains: "abc"}, 0, true},
+		{"skip limit 3", Filters{SkipLimit: 3, GameTitleContains: "abc"}, 1, true},
+		// games
+		{"game rating >= 2", Filters{MinGameRating: 2}, 1, true},
+		{"game rating >= 5", Filters{MinGameRating: 5}, 0, false},
+		{"exclude game", Filters{ExcludeGames: []string{"FOO DEF"}}, 1, true},
+		{"exclude games", Filters{ExcludeGames: []string{"foo abc", "foo def"}}, 0, false},
+		// metadata
+		{"composer of the game", Filters{ComposerContains: "Kondo"}, 1, true},
+		{"composer of the song", Filters{ComposerContains: "Someone"}, 1, true},
+		{"composer not found", Filters{ComposerContains: "Other"}, 0, false},
+		{"composer 'kondo'", Filters{ComposerContains: "kondo"}, 1, true},
+		{"platform wii", Filters{Platform: "wii"}, 1, true},
+		{"platform gamecube", Filters{Platform: "GameCube"}, 2, true},
+		{"platform not found", Filters{Platform: "PS"}, 0, false},
+		{"year >= 2005", Filters{MinYear: 2005}, 1, true},
+		{"year <= 2005", Filters{MaxYear: 2005}, 2, true},
+		{"year >= 2009", Filters{MinYear: 2009}, 0, false},
+		{"tag battle", Filters{Tags: []string{"battle"}}, 1, true},
+		{"tags battle and boss", Filters{Tags: []string{"battle", "BOSS"}}, 1, true},
+		{"tag town", Filters{Tags: []string{"town"}}, 2, true},
+		{"tags boss and town", Filters{Tags: []string{"boss", "town"}}, 0, false},
+		// query
+		{"query", Filters{Query: mustParseQuery("game:def AND duration>30 AND NOT tag:town")}, 3, true},
+		{"query rating", Filters{Query: mustParseQuery("rating>=4 OR plays>=2")}, 1, true},
+		{"query game rating", Filters{Query: mustParseQuery("game_rating<2")}, 2, true},
+		{"query not found", Filters{Query: mustParseQuery("title:xyz")}, 0, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -82,6 +136,54 @@ func TestInMemorySongRepository_getFirstFilteredSong(t *testing.T) {
 	}
 }
 
+func intPointer(i int) *int {
+	return &i
+}
+
+func TestInMemorySongRepository_neverPlayed(t *testing.T) {
+	game := Game{Title: "foo"}
+	r := InMemorySongRepository{
+		Songs: []Song{{Game: &game, Path: "played"}, {Game: &game, Path: "new"}},
+		RatingRepository: InMemoryRatingRepository{
+			PlayedSongs: []PlayedSong{{"played", []Play{{Timestamp: 100}}}},
+		},
+	}
+	assert.Equal(t, []int{1}, r.getFilteredSongs(Filters{MaxPlays: intPointer(0)}, []int{0, 1}, 0))
+	assert.Equal(t, []int{0, 1}, r.getFilteredSongs(Filters{}, []int{0, 1}, 0))
+}
+
+func mustParseQuery(s string) *Query {
+	q, err := ParseQuery(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func TestInMemorySongRepository_FilteredSongs(t *testing.T) {
+	game := Game{Title: "foo"}
+	r := InMemorySongRepository{
+		Songs: []Song{
+			{Title: "b", Game: &game, DurationSec: 10},
+			{Title: "c", Game: &game, DurationSec: 20},
+			{Title: "a", Game: &game, DurationSec: 30, IsPlayed: true},
+			{Title: "d", Game: &game, DurationSec: 40},
+		},
+	}
+	titles := func(songs []Song) []string {
+		rv := make([]string, len(songs))
+		for i, song := range songs {
+			rv[i] = song.Title
+		}
+		return rv
+	}
+	assert.Equal(t, []string{"b", "c", "d"}, titles(r.FilteredSongs(Filters{})))
+	assert.Equal(t, []string{"c", "d"}, titles(r.FilteredSongs(Filters{MinDurationSec: 15})))
+
+	r.Selection.Sort = SortOrder{Key: "duration", Descending: true}
+	assert.Equal(t, []string{"d", "c", "b"}, titles(r.FilteredSongs(Filters{})))
+}
+
 func TestInMemorySongRepository_GetRandomSong(t *testing.T) {
 	game1 := Game{Title: "foo abc"}
 	songs := []Song{
@@ -122,20 +224,30 @@ func Test_parseFile(t *testing.T) {
 		{
 			"1 entry",
 			"[{\"path\":\"path1\",\"timestamp\":123,\"title\":\"abc\",\"game_title\":\"ABC\",\"duration\":1.23,\"loop_start\":2,\"loop_end\":3,\"size\":4,\"error\":false}]",
-			[]parsedSongs{{"path1", "abc", "ABC", 1.23, 2, 3, 4, false}},
+			[]parsedSongs{{Path: "path1", Title: "abc", GameTitle: "ABC", DurationSec: 1.23, LoopStartMicro: 2, LoopEndMicro: 3, Size: 4}},
 		},
 		{
 			"2 entries",
 			"[{\"path\":\"path1\",\"timestamp\":123,\"title\":\"abc\",\"game_title\":\"ABC\",\"duration\":1,\"loop_start\":2,\"loop_end\":3,\"size\":4,\"error\":false},{\"path\":\"path2\",\"timestamp\":456,\"title\":\"def\",\"game_title\":\"DEF\",\"duration\":5,\"loop_start\":6,\"loop_end\":7,\"size\":8,\"error\":true}]",
 			[]parsedSongs{
-				{"path1", "abc", "ABC", 1, 2, 3, 4, false},
-				{"path2", "def", "DEF", 5, 6, 7, 8, true},
+				{Path: "path1", Title: "abc", GameTitle: "ABC", DurationSec: 1, LoopStartMicro: 2, LoopEndMicro: 3, Size: 4},
+				{Path: "path2", Title: "def", GameTitle: "DEF", DurationSec: 5, LoopStartMicro: 6, LoopEndMicro: 7, Size: 8, Error: true},
 			},
 		},
 		{
 			"no title",
 			"[{\"path\":\"path1\",\"timestamp\":123,\"title\":null,\"game_title\":null,\"duration\":1,\"loop_start\":2,\"loop_end\":3,\"size\":4,\"error\":false}]",
-			[]parsedSongs{{"path1", "", "", 1, 2, 3, 4, false}},
+			[]parsedSongs{{Path: "path1", Title: "", GameTitle: "", DurationSec: 1, LoopStartMicro: 2, LoopEndMicro: 3, Size: 4}},
+		},
+		{
+			"game metadata",
+			"[{\"path\":\"path1\",\"title\":\"abc\",\"game_title\":\"ABC\",\"size\":4,\"platform\":\"Wii\",\"year\":2008,\"composer\":\"Someone\",\"game_composer\":\"Kondo\",\"series\":\"Series\",\"game_rating\":4.5}]",
+			[]parsedSongs{{Path: "path1", Title: "abc", GameTitle: "ABC", Size: 4, Platform: "Wii", Year: 2008, Composer: "Someone", GameComposer: "Kondo", Series: "Series", GameRating: 4.5}},
+		},
+		{
+			"song metadata",
+			"[{\"path\":\"path1\",\"title\":\"abc\",\"game_title\":\"ABC\",\"size\":4,\"track\":3,\"disc\":2,\"tags\":[\"battle\",\"boss\"]}]",
+			[]parsedSongs{{Path: "path1", Title: "abc", GameTitle: "ABC", Size: 4, TrackNumber: 3, Disc: 2, Tags: []string{"battle", "boss"}}},
 		},
 	}
 	for _, tt := range tests {
@@ -148,6 +260,107 @@ func Test_parseFile(t *testing.T) {
 	}
 }
 
+func Test_convertImportedSongs_games(t *testing.T) {
+	parsed := []parseFileResult{{
+		absPath: "/root",
+		songs: []parsedSongs{
+			{Path: "a1", GameTitle: "A", Size: 1, Platform: "Wii"},
+			{Path: "b1", GameTitle: "B", Size: 1},
+			{Path: "a2", GameTitle: "A", Size: 1, Platform: "GameCube", Year: 2008, Series: "S", GameRating: 3},
+		},
+	}}
+	songs := convertImportedSongs(parsed)
+
+	assert.Len(t, songs, 3)
+	assert.Same(t, songs[0].Game, songs[2].Game)
+	assert.Equal(t, Game{Title: "A", Platform: "Wii", Year: 2008, Series: "S", Rating: 3}, *songs[0].Game)
+	assert.Equal(t, Game{Title: "B"}, *songs[1].Game)
+}
+
+func Test_convertImportedSongs_composers(t *testing.T) {
+	parsed := []parseFileResult{{
+		absPath: "/root",
+		songs: []parsedSongs{
+			{Path: "a1", GameTitle: "A", Size: 1, Composer: "Kondo"},
+			{Path: "a2", GameTitle: "A", Size: 1, Composer: "Minegishi"},
+			{Path: "b1", GameTitle: "B", Size: 1, GameComposer: "Kondo"},
+		},
+	}}
+	r := InMemorySongRepository{Songs: convertImportedSongs(parsed)}
+
+	tests := []struct {
+		name    string
+		filters Filters
+		want    []int
+	}{
+		{"composer of the song or of the game", Filters{ComposerContains: "Kondo"}, []int{0, 2}},
+		{"composer of the second song", Filters{ComposerContains: "Minegishi"}, []int{1}},
+		{"query", Filters{Query: mustParseQuery("composer:kondo")}, []int{0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.getFilteredSongs(tt.filters, []int{0, 1, 2}, 0))
+		})
+	}
+}
+
+func Test_makeSongFromImported(t *testing.T) {
+	game := Game{Title: "ABC", Composer: "Someone"}
+	parsed := parsedSongs{Path: "path1", Title: "abc", GameTitle: "ABC", DurationSec: 1.5, LoopStartMicro: 2, LoopEndMicro: 3, Size: 4, Composer: "Someone", TrackNumber: 3, Disc: 2, Tags: []string{"battle"}}
+	want := Song{Title: "abc", Game: &game, Composer: "Someone", TrackNumber: 3, Disc: 2, Tags: []string{"battle"}, DurationSec: 1.5, LoopStartMicro: 2, LoopEndMicro: 3, Path: "path1", AbsPath: "/root/path1"}
+	assert.Equal(t, want, makeSongFromImported(parsed, &game, "/root/path1"))
+}
+
+func Test_makeSongFromImported_gain(t *testing.T) {
+	zero := float32(0)
+	song := makeSongFromImported(parsedSongs{Path: "path1", GainDB: &zero}, &Game{}, "/root/path1")
+	assert.True(t, song.HasGain)
+	assert.Equal(t, float32(0), song.GainDB)
+
+	song = makeSongFromImported(parsedSongs{Path: "path1"}, &Game{}, "/root/path1")
+	assert.False(t, song.HasGain)
+}
+
+func TestInMemorySongRepository_GameRating(t *testing.T) {
+	game1 := Game{Title: "rated songs"}
+	game2 := Game{Title: "rated game", Rating: 5}
+	game3 := Game{Title: "no rating"}
+	r := InMemorySongRepository{
+		Songs: []Song{
+			{Game: &game1, Path: "a"},
+			{Game: &game1, Path: "b"},
+			{Game: &game1, Path: "c"},
+			{Game: &game2, Path: "d"},
+			{Game: &game3, Path: "e"},
+		},
+		RatingRepository: InMemoryRatingRepository{
+			PlayedSongs: []PlayedSong{
+				{"a", []Play{{Rating: 1}, {Rating: 2}}},
+				{"b", []Play{{Rating: 4}}},
+				{"d", []Play{{Rating: 1}}},
+				{"e", []Play{{Rating: 0}}},
+			},
+		},
+	}
+	tests := []struct {
+		name       string
+		game       *Game
+		wantRating float32
+		wantFound  bool
+	}{
+		{"mean of songs", &game1, 2.75, true},
+		{"rating of the game", &game2, 5, true},
+		{"no rating", &game3, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRating, gotFound := r.GameRating(tt.game)
+			assert.Equal(t, tt.wantRating, gotRating)
+			assert.Equal(t, tt.wantFound, gotFound)
+		})
+	}
+}
+
 func TestSongsFromFiles(t *testing.T) {
 	files := []string{"testdata/songs.json", "testdata/abc/songs.json"}
 	game1 := Game{Title: "ABC"}
@@ -155,10 +368,10 @@ func TestSongsFromFiles(t *testing.T) {
 	cwd, _ := os.Getwd()
 	cwd += "/"
 	want := []Song{
-		{"abc", &game1, 1, 2, 3, "hello/foo.brstm", cwd + "testdata/hello/foo.brstm", false},
-		{"def", &game2, 4, 5, 6, "bar.brstm", cwd + "testdata/bar.brstm", false},
-		{"abc", &game1, 1.23, 2, 3, "hello/foo.brstm", cwd + "testdata/abc/hello/foo.brstm", false},
-		{"def", &game2, 4, 5, 6, "bar.brstm", cwd + "testdata/abc/bar.brstm", false},
+		{Title: "abc", Game: &game1, DurationSec: 1, LoopStartMicro: 2, LoopEndMicro: 3, Path: "hello/foo.brstm", AbsPath: cwd + "testdata/hello/foo.brstm", MetadataFile: cwd + "testdata/songs.json"},
+		{Title: "def", Game: &game2, DurationSec: 4, LoopStartMicro: 5, LoopEndMicro: 6, Path: "bar.brstm", AbsPath: cwd + "testdata/bar.brstm", MetadataFile: cwd + "testdata/songs.json"},
+		{Title: "abc", Game: &game1, DurationSec: 1.23, LoopStartMicro: 2, LoopEndMicro: 3, Path: "hello/foo.brstm", AbsPath: cwd + "testdata/abc/hello/foo.brstm", MetadataFile: cwd + "testdata/abc/songs.json"},
+		{Title: "def", Game: &game2, DurationSec: 4, LoopStartMicro: 5, LoopEndMicro: 6, Path: "bar.brstm", AbsPath: cwd + "testdata/abc/bar.brstm", MetadataFile: cwd + "testdata/abc/songs.json"},
 	}
 	got := SongsFromFiles(files)
 	td.Cmp(t, got, want)
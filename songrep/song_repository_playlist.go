@@ -0,0 +1,53 @@
+package songrep
+
+// PlaylistSongRepository plays the songs of a playlist, in order unless
+// Shuffle is set. Its songs are made with ResolvePlaylist.
+type PlaylistSongRepository struct {
+	InMemorySongRepository
+	Shuffle bool
+}
+
+// ResolvePlaylist finds the songs of the playlist in the library. The paths
+// that don't resolve anymore, because the song is no longer in the metadata
+// files or its file has been removed, are returned as missing.
+func ResolvePlaylist(playlist Playlist, library []Song) (songs []Song, missing []string) {
+	byPath := make(map[string]Song, len(library))
+	for _, song := range library {
+		byPath[song.Path] = song
+	}
+
+	songs = make([]Song, 0, len(playlist.Paths))
+	missing = make([]string, 0)
+	for _, path := range playlist.Paths {
+		song, found := byPath[path]
+		if found && song.AbsPath != "" {
+			// a file that can't be read can't be played either
+			found, _ = fileExists(song.AbsPath)
+		}
+		if !found {
+			missing = append(missing, path)
+			continue
+		}
+		songs = append(songs, song)
+	}
+	return songs, missing
+}
+
+// GetRandomSong returns the next song of the playlist that matches the
+// filters, or a random one if Shuffle is set.
+func (r *PlaylistSongRepository) GetRandomSong(filters Filters) (Song, bool) {
+	if r.Shuffle {
+		return r.InMemorySongRepository.GetRandomSong(filters)
+	}
+
+	indices := make([]int, len(r.Songs))
+	for i := range indices {
+		indices[i] = i
+	}
+	index, found := r.getFirstFilteredSong(filters, indices)
+	if found {
+		r.Songs[index].IsPlayed = true
+		return r.Songs[index], true
+	}
+	return Song{}, false
+}
@@ -0,0 +1,60 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolvePlaylist(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "a.brstm")
+	assert.NoError(t, os.WriteFile(existing, []byte{}, 0600))
+
+	game := Game{Title: "game"}
+	library := []Song{
+		{Title: "a", Game: &game, Path: "a.brstm", AbsPath: existing},
+		{Title: "b", Game: &game, Path: "b.brstm", AbsPath: filepath.Join(dir, "b.brstm")},
+		{Title: "c", Game: &game, Path: "c.brstm"},
+	}
+	playlist := Playlist{Name: "p", Paths: []string{"c.brstm", "a.brstm", "b.brstm", "gone.brstm", "c.brstm"}}
+
+	songs, missing := ResolvePlaylist(playlist, library)
+	titles := make([]string, len(songs))
+	for i, song := range songs {
+		titles[i] = song.Title
+	}
+	assert.Equal(t, []string{"c", "a", "c"}, titles)
+	assert.Equal(t, []string{"b.brstm", "gone.brstm"}, missing)
+}
+
+func TestPlaylistSongRepository_GetRandomSong(t *testing.T) {
+	game := Game{Title: "game"}
+	makeRepository := func(shuffle bool) PlaylistSongRepository {
+		return PlaylistSongRepository{
+			InMemorySongRepository: InMemorySongRepository{
+				Songs: []Song{
+					{Title: "c", Game: &game, Path: "c", DurationSec: 10},
+					{Title: "a", Game: &game, Path: "a", DurationSec: 20},
+					{Title: "b", Game: &game, Path: "b", DurationSec: 30},
+				},
+			},
+			Shuffle: shuffle,
+		}
+	}
+	playAll := func(r PlaylistSongRepository, filters Filters) []string {
+		titles := make([]string, 0)
+		for {
+			song, found := r.GetRandomSong(filters)
+			if !found {
+				return titles
+			}
+			titles = append(titles, song.Title)
+		}
+	}
+
+	assert.Equal(t, []string{"c", "a", "b"}, playAll(makeRepository(false), Filters{}))
+	assert.Equal(t, []string{"a", "b"}, playAll(makeRepository(false), Filters{MinDurationSec: 20}))
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, playAll(makeRepository(true), Filters{}))
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -52,26 +53,7 @@ func downloadSongMetadata(url, songDir, username, password string, filters Filte
 	setAuthHeader(req, username, password)
 	client := &http.Client{}
 
-	values := req.URL.Query()
-	if filters.MinRating > 0. {
-		values.Set("min_rating", strconv.Itoa(int(filters.MinRating)))
-	}
-	if filters.MinDurationSec > 0. {
-		values.Set("min_duration", strconv.Itoa(filters.MinDurationSec))
-	}
-	if filters.OnlyHasRating {
-		values.Set("only_has_rating", "true")
-	}
-	if filters.OnlyHasNoRating {
-		values.Set("only_has_no_rating", "true")
-	}
-	if len(filters.TitleContains) > 0 {
-		values.Set("title_contains", filters.TitleContains)
-	}
-	if len(filters.GameTitleContains) > 0 {
-		values.Set("game_title_contains", filters.GameTitleContains)
-	}
-	req.URL.RawQuery = values.Encode()
+	req.URL.RawQuery = filterValues(filters).Encode()
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -98,13 +80,21 @@ func downloadSongMetadata(url, songDir, username, password string, filters Filte
 	}
 
 	type songResponse struct {
-		Id        string  `json:"id"`
-		Title     string  `json:"title"`
-		GameTitle string  `json:"game_title"`
-		Duration  float32 `json:"duration"`
-		LoopStart int     `json:"loop_start"`
-		LoopEnd   int     `json:"loop_end"`
-		Path      string  `json:"path"`
+		Id           string   `json:"id"`
+		Title        string   `json:"title"`
+		GameTitle    string   `json:"game_title"`
+		Duration     float32  `json:"duration"`
+		LoopStart    int      `json:"loop_start"`
+		LoopEnd      int      `json:"loop_end"`
+		Path         string   `json:"path"`
+		Platform     string   `json:"platform"`
+		Year         int      `json:"year"`
+		Composer     string   `json:"composer"`
+		GameComposer string   `json:"game_composer"`
+		Series       string   `json:"series"`
+		Track        int      `json:"track"`
+		Disc         int      `json:"disc"`
+		Tags         []string `json:"tags"`
 	}
 
 	var songResp songResponse
@@ -116,8 +106,16 @@ func downloadSongMetadata(url, songDir, username, password string, filters Filte
 	song := Song{
 		Title: songResp.Title,
 		Game: &Game{
-			Title: songResp.GameTitle,
+			Title:    songResp.GameTitle,
+			Platform: songResp.Platform,
+			Year:     songResp.Year,
+			Composer: songResp.GameComposer,
+			Series:   songResp.Series,
 		},
+		Composer:       songResp.Composer,
+		TrackNumber:    songResp.Track,
+		Disc:           songResp.Disc,
+		Tags:           songResp.Tags,
 		DurationSec:    songResp.Duration,
 		LoopStartMicro: songResp.LoopStart,
 		LoopEndMicro:   songResp.LoopEnd,
@@ -128,6 +126,81 @@ func downloadSongMetadata(url, songDir, username, password string, filters Filte
 	return song, songResp.Id, true
 }
 
+// filterValues are the query parameters of the filters sent to the server.
+func filterValues(filters Filters) url.Values {
+	values := url.Values{}
+	if filters.MinRating > 0. {
+		values.Set("min_rating", strconv.Itoa(int(filters.MinRating)))
+	}
+	if filters.MaxRating > 0. {
+		values.Set("max_rating", strconv.FormatFloat(float64(filters.MaxRating), 'f', -1, 32))
+	}
+	if filters.MinDurationSec > 0. {
+		values.Set("min_duration", strconv.Itoa(filters.MinDurationSec))
+	}
+	if filters.MaxDurationSec > 0 {
+		values.Set("max_duration", strconv.Itoa(filters.MaxDurationSec))
+	}
+	if filters.MinPlays > 0 {
+		values.Set("min_plays", strconv.Itoa(filters.MinPlays))
+	}
+	if filters.MaxPlays != nil {
+		values.Set("max_plays", strconv.Itoa(*filters.MaxPlays))
+	}
+	if filters.PlayedSince > 0 {
+		values.Set("played_since", strconv.Itoa(filters.PlayedSince))
+	}
+	if filters.NotPlayedSince > 0 {
+		values.Set("not_played_since", strconv.Itoa(filters.NotPlayedSince))
+	}
+	if filters.OnlyHasRating {
+		values.Set("only_has_rating", "true")
+	}
+	if filters.OnlyHasNoRating {
+		values.Set("only_has_no_rating", "true")
+	}
+	if len(filters.TitleContains) > 0 {
+		values.Set("title_contains", filters.TitleContains)
+	}
+	if len(filters.GameTitleContains) > 0 {
+		values.Set("game_title_contains", filters.GameTitleContains)
+	}
+	if filters.SkipLimit > 0 {
+		values.Set("skip_limit", strconv.Itoa(filters.SkipLimit))
+	}
+	if filters.MinGameRating > 0. {
+		values.Set("min_game_rating", strconv.FormatFloat(float64(filters.MinGameRating), 'f', -1, 32))
+	}
+	for _, game := range filters.ExcludeGames {
+		values.Add("exclude_game", game)
+	}
+	if len(filters.ComposerContains) > 0 {
+		values.Set("composer_contains", filters.ComposerContains)
+	}
+	if len(filters.Platform) > 0 {
+		values.Set("platform", filters.Platform)
+	}
+	if filters.MinYear > 0 {
+		values.Set("min_year", strconv.Itoa(filters.MinYear))
+	}
+	if filters.MaxYear > 0 {
+		values.Set("max_year", strconv.Itoa(filters.MaxYear))
+	}
+	for _, tag := range filters.Tags {
+		values.Add("tag", tag)
+	}
+	if filters.TitleMatch != MatchInsensitive {
+		values.Set("title_match", filters.TitleMatch.String())
+	}
+	if filters.GameTitleMatch != MatchInsensitive {
+		values.Set("game_title_match", filters.GameTitleMatch.String())
+	}
+	if filters.ComposerMatch != MatchInsensitive {
+		values.Set("composer_match", filters.ComposerMatch.String())
+	}
+	return values
+}
+
 func downloadSongFile(url, filePath, username, password string) error {
 	req, _ := http.NewRequest("GET", url, nil)
 	setAuthHeader(req, username, password)
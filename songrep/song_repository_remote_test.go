@@ -0,0 +1,29 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_filterValues(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters Filters
+		want    string
+	}{
+		{"no filter", Filters{}, ""},
+		{"rating", Filters{MinRating: 3, OnlyHasRating: true}, "min_rating=3&only_has_rating=true"},
+		{"ranges", Filters{MinRating: 1, MaxRating: 4.5, MinDurationSec: 10, MaxDurationSec: 90}, "max_duration=90&max_rating=4.5&min_duration=10&min_rating=1"},
+		{"plays", Filters{MinPlays: 1, MaxPlays: intPointer(5), PlayedSince: 1672531200, NotPlayedSince: 1675209600}, "max_plays=5&min_plays=1&not_played_since=1675209600&played_since=1672531200"},
+		{"never played", Filters{MaxPlays: intPointer(0)}, "max_plays=0"},
+		{"titles", Filters{TitleContains: "a b", GameTitleContains: "c"}, "game_title_contains=c&title_contains=a+b"},
+		{"games", Filters{MinGameRating: 3.5, ExcludeGames: []string{"a", "b"}}, "exclude_game=a&exclude_game=b&min_game_rating=3.5"},
+		{"metadata", Filters{ComposerContains: "Kondo", Platform: "Wii", MinYear: 2000, MaxYear: 2010, Tags: []string{"battle", "boss"}}, "composer_contains=Kondo&max_year=2010&min_year=2000&platform=Wii&tag=battle&tag=boss"},
+		{"match modes", Filters{TitleContains: "a", TitleMatch: MatchRegexp, GameTitleMatch: MatchNormalized}, "game_title_match=normalized&title_contains=a&title_match=regexp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, filterValues(tt.filters).Encode())
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package songrep
+
+import (
+	"fmt"
+)
+
+// The kinds of problems found by Validate, and by the header checks of the
+// validate command.
+const (
+	ProblemLoopPoints  = "loop_points"
+	ProblemHeader      = "header"
+	ProblemMissingFile = "missing_file"
+	ProblemUnreadable  = "unreadable"
+	ProblemDuplicate   = "duplicate_path"
+)
+
+// loopEndToleranceMicro is how far the loop end can be after the end of the
+// song, the durations of the metadata files being rounded.
+const loopEndToleranceMicro = 10000
+
+// Problem is an error in the metadata of a song.
+type Problem struct {
+	Path         string `json:"path"`
+	MetadataFile string `json:"metadata_file"`
+	Kind         string `json:"kind"`
+	Message      string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s", p.Path, p.Message)
+}
+
+// Validate finds the songs whose loop points are impossible, whose file is
+// missing or can't be read, or that are described twice.
+func Validate(songs []Song) []Problem {
+	problems := make([]Problem, 0)
+	add := func(song Song, kind, message string) {
+		problems = append(problems, Problem{Path: song.Path, MetadataFile: song.MetadataFile, Kind: kind, Message: message})
+	}
+
+	seen := make(map[string]Song, len(songs))
+	for _, song := range songs {
+		for _, message := range loopProblems(song) {
+			add(song, ProblemLoopPoints, message)
+		}
+		if song.AbsPath != "" {
+			if exists, err := fileExists(song.AbsPath); err != nil {
+				add(song, ProblemUnreadable, err.Error())
+			} else if !exists {
+				add(song, ProblemMissingFile, "the file doesn't exist")
+			}
+		}
+		if first, found := seen[song.AbsPath]; found {
+			add(song, ProblemDuplicate, fmt.Sprintf("the song is also in %s", first.MetadataFile))
+		} else {
+			seen[song.AbsPath] = song
+		}
+	}
+	return problems
+}
+
+func loopProblems(song Song) []string {
+	problems := make([]string, 0)
+	if song.LoopStartMicro < 0 || song.LoopEndMicro < 0 {
+		return append(problems, fmt.Sprintf("negative loop point (%dµs to %dµs)", song.LoopStartMicro, song.LoopEndMicro))
+	}
+	if song.LoopEndMicro != 0 && song.LoopStartMicro >= song.LoopEndMicro {
+		problems = append(problems, fmt.Sprintf("loop start %dµs is not before the loop end %dµs", song.LoopStartMicro, song.LoopEndMicro))
+	}
+	if song.DurationSec <= 0 {
+		return problems
+	}
+	durationMicro := int(float64(song.DurationSec) * 1000000)
+	if song.LoopEndMicro > durationMicro+loopEndToleranceMicro {
+		problems = append(problems, fmt.Sprintf("loop end %dµs is after the end of the song (%dµs)", song.LoopEndMicro, durationMicro))
+	}
+	if song.LoopStartMicro >= durationMicro {
+		problems = append(problems, fmt.Sprintf("loop start %dµs is after the end of the song (%dµs)", song.LoopStartMicro, durationMicro))
+	}
+	return problems
+}
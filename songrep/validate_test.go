@@ -0,0 +1,52 @@
+package songrep
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_loopProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		song Song
+		want []string
+	}{
+		{"no loop points", Song{DurationSec: 10}, []string{}},
+		{"valid", Song{DurationSec: 10, LoopStartMicro: 1000000, LoopEndMicro: 9000000}, []string{}},
+		{"loop end at the rounded end", Song{DurationSec: 10, LoopEndMicro: 10004000}, []string{}},
+		{"loop start only", Song{DurationSec: 10, LoopStartMicro: 1000000}, []string{}},
+		{"negative", Song{DurationSec: 10, LoopStartMicro: -1}, []string{"negative loop point (-1µs to 0µs)"}},
+		{"start after end", Song{DurationSec: 10, LoopStartMicro: 5000000, LoopEndMicro: 4000000}, []string{"loop start 5000000µs is not before the loop end 4000000µs"}},
+		{"end after the song", Song{DurationSec: 10, LoopStartMicro: 1000000, LoopEndMicro: 12000000}, []string{"loop end 12000000µs is after the end of the song (10000000µs)"}},
+		{"start after the song", Song{DurationSec: 10, LoopStartMicro: 11000000}, []string{"loop start 11000000µs is after the end of the song (10000000µs)"}},
+		{"unknown duration", Song{LoopStartMicro: 11000000, LoopEndMicro: 12000000}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, loopProblems(tt.song))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.brstm"), []byte{}, 0600))
+	songs := []Song{
+		{Path: "a.brstm", AbsPath: filepath.Join(dir, "a.brstm"), MetadataFile: "first.json", DurationSec: 10},
+		{Path: "b.brstm", AbsPath: filepath.Join(dir, "b.brstm"), MetadataFile: "first.json", DurationSec: 10, LoopStartMicro: 3, LoopEndMicro: 2},
+		{Path: "a.brstm", AbsPath: filepath.Join(dir, "a.brstm"), MetadataFile: "second.json", DurationSec: 10},
+		// a.brstm is not a directory
+		{Path: "a.brstm/c.brstm", AbsPath: filepath.Join(dir, "a.brstm", "c.brstm"), MetadataFile: "first.json", DurationSec: 10},
+	}
+	want := []Problem{
+		{Path: "b.brstm", MetadataFile: "first.json", Kind: ProblemLoopPoints, Message: "loop start 3µs is not before the loop end 2µs"},
+		{Path: "b.brstm", MetadataFile: "first.json", Kind: ProblemMissingFile, Message: "the file doesn't exist"},
+		{Path: "a.brstm", MetadataFile: "second.json", Kind: ProblemDuplicate, Message: "the song is also in first.json"},
+	}
+	problems := Validate(songs)
+	assert.Equal(t, want, problems[:3])
+	assert.Len(t, problems, 4)
+	assert.Equal(t, ProblemUnreadable, problems[3].Kind)
+}
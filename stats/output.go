@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"text/tabwriter"
+	"time"
+)
+
+func WriteJSON(writer io.Writer, report Report) {
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	_, err = writer.Write(append(content, '\n'))
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func WriteText(writer io.Writer, report Report) {
+	tw := tabwriter.NewWriter(writer, 0, 4, 2, ' ', 0)
+	p := func(format string, a ...any) {
+		_, err := fmt.Fprintf(tw, format, a...)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	p("Songs:\t%d\n", report.TotalSongs)
+	p("Plays:\t%d\n", report.TotalPlays)
+	p("Listening time:\t%s\n", time.Duration(report.TotalListeningSec*float64(time.Second)).Round(time.Second))
+	p("Never played:\t%d\n", len(report.NeverPlayed))
+
+	p("\nPLAYS PER MONTH\n")
+	for _, c := range report.PlaysPerMonth {
+		p("%s\t%d\n", c.Period, c.Plays)
+	}
+
+	p("\nPLAYS PER WEEK\n")
+	for _, c := range report.PlaysPerWeek {
+		p("%s\t%d\n", c.Period, c.Plays)
+	}
+
+	p("\nPLAYS PER DAY\n")
+	for _, c := range report.PlaysPerDay {
+		p("%s\t%d\n", c.Period, c.Plays)
+	}
+
+	p("\nRATINGS\n")
+	for _, c := range report.RatingDistribution {
+		p("%d\t%d\n", c.Rating, c.Count)
+	}
+
+	p("\nMOST PLAYED SONGS\n")
+	p("Plays\tRating\tGame\tTitle\n")
+	for _, s := range report.MostPlayedSongs {
+		p("%d\t%s\t%s\t%s\n", s.Plays, formatRating(s.Rating, s.HasRating), s.GameTitle, songTitle(s))
+	}
+
+	p("\nHIGHEST RATED SONGS\n")
+	p("Rating\tPlays\tGame\tTitle\n")
+	for _, s := range report.HighestRatedSongs {
+		p("%s\t%d\t%s\t%s\n", formatRating(s.Rating, s.HasRating), s.Plays, s.GameTitle, songTitle(s))
+	}
+
+	p("\nMOST PLAYED GAMES\n")
+	p("Plays\tRating\tSongs\tGame\n")
+	for _, g := range report.MostPlayedGames {
+		p("%d\t%s\t%d\t%s\n", g.Plays, formatRating(g.Rating, g.HasRating), g.Songs, g.Title)
+	}
+
+	p("\nHIGHEST RATED GAMES\n")
+	p("Rating\tPlays\tSongs\tGame\n")
+	for _, g := range report.HighestRatedGames {
+		p("%s\t%d\t%d\t%s\n", formatRating(g.Rating, g.HasRating), g.Plays, g.Songs, g.Title)
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func formatRating(rating float32, hasRating bool) string {
+	if !hasRating {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", rating)
+}
+
+// songTitle falls back on the path for songs that are not in the library.
+func songTitle(s SongStat) string {
+	if s.Title == "" {
+		return s.Path
+	}
+	return s.Title
+}
@@ -0,0 +1,274 @@
+package stats
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"vgsgo/songrep"
+)
+
+type Options struct {
+	// Top is the length of the lists of most played and highest rated songs
+	// and games.
+	Top int
+	// Location is the time zone used to group plays by day, week and month.
+	Location *time.Location
+}
+
+type Report struct {
+	TotalSongs         int           `json:"total_songs"`
+	TotalPlays         int           `json:"total_plays"`
+	TotalListeningSec  float64       `json:"total_listening_sec"`
+	PlaysPerDay        []PeriodCount `json:"plays_per_day"`
+	PlaysPerWeek       []PeriodCount `json:"plays_per_week"`
+	PlaysPerMonth      []PeriodCount `json:"plays_per_month"`
+	MostPlayedSongs    []SongStat    `json:"most_played_songs"`
+	HighestRatedSongs  []SongStat    `json:"highest_rated_songs"`
+	MostPlayedGames    []GameStat    `json:"most_played_games"`
+	HighestRatedGames  []GameStat    `json:"highest_rated_games"`
+	RatingDistribution []RatingCount `json:"rating_distribution"`
+	NeverPlayed        []SongStat    `json:"never_played"`
+}
+
+type PeriodCount struct {
+	Period string `json:"period"`
+	Plays  int    `json:"plays"`
+}
+
+type SongStat struct {
+	Path      string  `json:"path"`
+	Title     string  `json:"title"`
+	GameTitle string  `json:"game_title"`
+	Plays     int     `json:"plays"`
+	Rating    float32 `json:"rating"`
+	HasRating bool    `json:"has_rating"`
+}
+
+type GameStat struct {
+	Title     string  `json:"title"`
+	Songs     int     `json:"songs"`
+	Plays     int     `json:"plays"`
+	Rating    float32 `json:"rating"`
+	HasRating bool    `json:"has_rating"`
+}
+
+type RatingCount struct {
+	Rating int `json:"rating"`
+	Count  int `json:"count"`
+}
+
+// Compute aggregates the plays of the rating repository. Plays of songs that
+// are not in the library are counted in the totals and per period, but their
+// listening time is unknown unless it has been recorded with the play.
+func Compute(songs []songrep.Song, ratings *songrep.InMemoryRatingRepository, opts Options) Report {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	songsByPath := make(map[string]songrep.Song, len(songs))
+	for _, song := range songs {
+		songsByPath[song.Path] = song
+	}
+
+	report := Report{TotalSongs: len(songs)}
+	perDay := make(map[string]int)
+	perWeek := make(map[string]int)
+	perMonth := make(map[string]int)
+	perRating := make(map[int]int)
+	songStats := make([]SongStat, 0, len(ratings.PlayedSongs))
+	played := make(map[string]bool, len(ratings.PlayedSongs))
+
+	for _, playedSong := range ratings.PlayedSongs {
+		song, inLibrary := songsByPath[playedSong.Path]
+		if !inLibrary {
+			song = songrep.Song{Path: playedSong.Path}
+		}
+		for _, play := range playedSong.Plays {
+			t := time.Unix(int64(play.Timestamp), 0).In(loc)
+			year, week := t.ISOWeek()
+			perDay[t.Format("2006-01-02")]++
+			perWeek[fmt.Sprintf("%04d-W%02d", year, week)]++
+			perMonth[t.Format("2006-01")]++
+			if play.Rating > 0 {
+				perRating[play.Rating]++
+			}
+			if play.ListenedSec > 0 {
+				report.TotalListeningSec += float64(play.ListenedSec)
+			} else {
+				report.TotalListeningSec += float64(song.DurationSec)
+			}
+		}
+		report.TotalPlays += len(playedSong.Plays)
+		if len(playedSong.Plays) > 0 {
+			played[playedSong.Path] = true
+			songStats = append(songStats, makeSongStat(song, ratings))
+		}
+	}
+
+	report.PlaysPerDay = sortPeriods(perDay)
+	report.PlaysPerWeek = sortPeriods(perWeek)
+	report.PlaysPerMonth = sortPeriods(perMonth)
+	report.RatingDistribution = sortRatings(perRating)
+
+	report.MostPlayedSongs = mostPlayedSongs(songStats, opts.Top)
+	report.HighestRatedSongs = highestRatedSongs(songStats, opts.Top)
+
+	gameStats := computeGameStats(songs, songStats)
+	report.MostPlayedGames = mostPlayedGames(gameStats, opts.Top)
+	report.HighestRatedGames = highestRatedGames(gameStats, opts.Top)
+
+	report.NeverPlayed = make([]SongStat, 0)
+	for _, song := range songs {
+		if !played[song.Path] {
+			report.NeverPlayed = append(report.NeverPlayed, makeSongStat(song, ratings))
+		}
+	}
+
+	return report
+}
+
+func makeSongStat(song songrep.Song, ratings *songrep.InMemoryRatingRepository) SongStat {
+	stat := SongStat{
+		Path:  song.Path,
+		Title: song.Title,
+		Plays: len(ratings.Plays(song)),
+	}
+	if song.Game != nil {
+		stat.GameTitle = song.Game.Title
+	}
+	stat.Rating, stat.HasRating = ratings.Rating(song)
+	return stat
+}
+
+// computeGameStats sums the plays of the songs of each game. The rating of a
+// game is its own rating if it has one, otherwise the mean of the ratings of
+// its songs.
+func computeGameStats(songs []songrep.Song, songStats []SongStat) []GameStat {
+	games := make(map[string]*GameStat)
+	ratingTotals := make(map[string]float32)
+	ratedSongs := make(map[string]int)
+	gameOf := func(title string) *GameStat {
+		if _, found := games[title]; !found {
+			games[title] = &GameStat{Title: title}
+		}
+		return games[title]
+	}
+
+	explicitRatings := make(map[string]float32)
+	for _, song := range songs {
+		if song.Game != nil {
+			gameOf(song.Game.Title).Songs++
+			if song.Game.Rating > 0 {
+				explicitRatings[song.Game.Title] = song.Game.Rating
+			}
+		}
+	}
+	for _, stat := range songStats {
+		if stat.GameTitle == "" {
+			continue
+		}
+		game := gameOf(stat.GameTitle)
+		game.Plays += stat.Plays
+		if stat.HasRating {
+			ratingTotals[stat.GameTitle] += stat.Rating
+			ratedSongs[stat.GameTitle]++
+			game.HasRating = true
+		}
+	}
+
+	rv := make([]GameStat, 0, len(games))
+	for title, game := range games {
+		if rating, found := explicitRatings[title]; found {
+			game.Rating = rating
+			game.HasRating = true
+		} else if game.HasRating {
+			game.Rating = ratingTotals[title] / float32(ratedSongs[title])
+		}
+		rv = append(rv, *game)
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Title < rv[j].Title })
+	return rv
+}
+
+func sortPeriods(counts map[string]int) []PeriodCount {
+	rv := make([]PeriodCount, 0, len(counts))
+	for period, count := range counts {
+		rv = append(rv, PeriodCount{period, count})
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Period < rv[j].Period })
+	return rv
+}
+
+func sortRatings(counts map[int]int) []RatingCount {
+	rv := make([]RatingCount, 0, len(counts))
+	for rating, count := range counts {
+		rv = append(rv, RatingCount{rating, count})
+	}
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Rating < rv[j].Rating })
+	return rv
+}
+
+func mostPlayedSongs(stats []SongStat, top int) []SongStat {
+	rv := append([]SongStat{}, stats...)
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].Plays != rv[j].Plays {
+			return rv[i].Plays > rv[j].Plays
+		}
+		return rv[i].Path < rv[j].Path
+	})
+	return truncate(rv, top)
+}
+
+func highestRatedSongs(stats []SongStat, top int) []SongStat {
+	rv := make([]SongStat, 0, len(stats))
+	for _, stat := range stats {
+		if stat.HasRating {
+			rv = append(rv, stat)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].Rating != rv[j].Rating {
+			return rv[i].Rating > rv[j].Rating
+		}
+		if rv[i].Plays != rv[j].Plays {
+			return rv[i].Plays > rv[j].Plays
+		}
+		return rv[i].Path < rv[j].Path
+	})
+	return truncate(rv, top)
+}
+
+func mostPlayedGames(stats []GameStat, top int) []GameStat {
+	rv := make([]GameStat, 0, len(stats))
+	for _, stat := range stats {
+		if stat.Plays > 0 {
+			rv = append(rv, stat)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool { return rv[i].Plays > rv[j].Plays })
+	return truncate(rv, top)
+}
+
+func highestRatedGames(stats []GameStat, top int) []GameStat {
+	rv := make([]GameStat, 0, len(stats))
+	for _, stat := range stats {
+		if stat.HasRating {
+			rv = append(rv, stat)
+		}
+	}
+	sort.SliceStable(rv, func(i, j int) bool {
+		if rv[i].Rating != rv[j].Rating {
+			return rv[i].Rating > rv[j].Rating
+		}
+		return rv[i].Plays > rv[j].Plays
+	})
+	return truncate(rv, top)
+}
+
+func truncate[T any](s []T, top int) []T {
+	if top > 0 && len(s) > top {
+		return s[:top]
+	}
+	return s
+}
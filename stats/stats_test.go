@@ -0,0 +1,88 @@
+package stats
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"vgsgo/songrep"
+)
+
+func makeTestData() ([]songrep.Song, *songrep.InMemoryRatingRepository) {
+	gameA := songrep.Game{Title: "Game A"}
+	gameB := songrep.Game{Title: "Game B"}
+	gameC := songrep.Game{Title: "Game C", Rating: 4.5}
+	songs := []songrep.Song{
+		{Title: "a1", Game: &gameA, DurationSec: 100, Path: "a1.brstm"},
+		{Title: "a2", Game: &gameA, DurationSec: 50, Path: "a2.brstm"},
+		{Title: "b1", Game: &gameB, DurationSec: 200, Path: "b1.brstm"},
+		{Title: "b2", Game: &gameB, DurationSec: 30, Path: "b2.brstm"},
+		{Title: "c1", Game: &gameC, DurationSec: 10, Path: "c1.brstm"},
+	}
+
+	t0 := 1672653600 // 2023-01-02 10:00 UTC, a monday
+	day := 24 * 60 * 60
+	ratings := songrep.InMemoryRatingRepository{
+		PlayedSongs: []songrep.PlayedSong{
+			{Path: "a1.brstm", Plays: []songrep.Play{{Timestamp: t0, Rating: 5}, {Timestamp: t0 + day, ListenedSec: 20, Skipped: true}, {Timestamp: t0 + 31*day, Rating: 3}}},
+			{Path: "a2.brstm", Plays: []songrep.Play{{Timestamp: t0, Rating: 2}}},
+			{Path: "b1.brstm", Plays: []songrep.Play{{Timestamp: t0 + day, Rating: 4}}},
+			{Path: "gone.brstm", Plays: []songrep.Play{{Timestamp: t0, Rating: 1}}},
+		},
+	}
+	return songs, &ratings
+}
+
+func TestCompute(t *testing.T) {
+	songs, ratings := makeTestData()
+	got := Compute(songs, ratings, Options{Top: 2})
+
+	assert.Equal(t, 5, got.TotalSongs)
+	assert.Equal(t, 6, got.TotalPlays)
+	assert.InDelta(t, 470.0, got.TotalListeningSec, 0.001)
+	assert.Equal(t, []PeriodCount{{"2023-01-02", 3}, {"2023-01-03", 2}, {"2023-02-02", 1}}, got.PlaysPerDay)
+	assert.Equal(t, []PeriodCount{{"2023-W01", 5}, {"2023-W05", 1}}, got.PlaysPerWeek)
+	assert.Equal(t, []PeriodCount{{"2023-01", 5}, {"2023-02", 1}}, got.PlaysPerMonth)
+	assert.Equal(t, []RatingCount{{1, 1}, {2, 1}, {3, 1}, {4, 1}, {5, 1}}, got.RatingDistribution)
+	assert.Equal(t, []SongStat{
+		{Path: "a1.brstm", Title: "a1", GameTitle: "Game A", Plays: 3, Rating: 4, HasRating: true},
+		{Path: "a2.brstm", Title: "a2", GameTitle: "Game A", Plays: 1, Rating: 2, HasRating: true},
+	}, got.MostPlayedSongs)
+	assert.Equal(t, []SongStat{
+		{Path: "a1.brstm", Title: "a1", GameTitle: "Game A", Plays: 3, Rating: 4, HasRating: true},
+		{Path: "b1.brstm", Title: "b1", GameTitle: "Game B", Plays: 1, Rating: 4, HasRating: true},
+	}, got.HighestRatedSongs)
+	assert.Equal(t, []GameStat{
+		{Title: "Game A", Songs: 2, Plays: 4, Rating: 3, HasRating: true},
+		{Title: "Game B", Songs: 2, Plays: 1, Rating: 4, HasRating: true},
+	}, got.MostPlayedGames)
+	assert.Equal(t, []GameStat{
+		{Title: "Game C", Songs: 1, Plays: 0, Rating: 4.5, HasRating: true},
+		{Title: "Game B", Songs: 2, Plays: 1, Rating: 4, HasRating: true},
+	}, got.HighestRatedGames)
+	assert.Equal(t, []SongStat{
+		{Path: "b2.brstm", Title: "b2", GameTitle: "Game B"},
+		{Path: "c1.brstm", Title: "c1", GameTitle: "Game C"},
+	}, got.NeverPlayed)
+}
+
+func TestCompute_noPlay(t *testing.T) {
+	songs, _ := makeTestData()
+	got := Compute(songs, &songrep.InMemoryRatingRepository{}, Options{})
+
+	assert.Equal(t, 0, got.TotalPlays)
+	assert.Equal(t, []PeriodCount{}, got.PlaysPerDay)
+	assert.Equal(t, []SongStat{}, got.MostPlayedSongs)
+	assert.Equal(t, []GameStat{}, got.MostPlayedGames)
+	assert.Len(t, got.NeverPlayed, 5)
+}
+
+func TestWriteText(t *testing.T) {
+	songs, ratings := makeTestData()
+	buf := bytes.NewBuffer([]byte{})
+	WriteText(buf, Compute(songs, ratings, Options{Top: 2}))
+	got := buf.String()
+
+	assert.True(t, strings.Contains(got, "Listening time:  7m50s\n"), got)
+	assert.True(t, strings.Contains(got, "\n3      4.00    Game A  a1\n"), got)
+}